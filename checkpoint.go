@@ -0,0 +1,226 @@
+package dockertest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Checkpointer is implemented by containers that can save their state under a name and
+// reset to it later, for example in the setup of each test.
+type Checkpointer interface {
+	// Checkpoint saves the current state under name, replacing an earlier checkpoint of that name.
+	Checkpoint(name string) error
+	// Restore resets the state to the checkpoint name.
+	Restore(name string) error
+}
+
+var (
+	_ Checkpointer = (*Container)(nil)
+	_ Checkpointer = (*PostgreSQLContainer)(nil)
+	_ Checkpointer = (*MySQLContainer)(nil)
+	_ Checkpointer = (*RedisContainer)(nil)
+)
+
+// Checkpoint commits the container's file system to an image with "docker commit".
+// Data in volumes, which most database images declare for their data directory, is not part
+// of the checkpoint; the service specific containers offer checkpoints that include it.
+func (c *Container) Checkpoint(name string) error {
+	image := fmt.Sprintf("dockertest-checkpoint/%s:%s", shortID(c.ContainerID), nonIdentifier.ReplaceAllString(strings.ToLower(name), "_"))
+	if err := runDockerCommand(c.pool.log(), "docker", "commit", string(c.ContainerID), image).Run(); err != nil {
+		return fmt.Errorf("Error committing checkpoint %s: %v", name, err)
+	}
+	if c.checkpoints == nil {
+		c.checkpoints = map[string]string{}
+	}
+	c.checkpoints[name] = image
+	return nil
+}
+
+// Restore replaces the container with a new one created from the checkpoint image. The new
+// container has the same name, options and host port, but a new ContainerID.
+func (c *Container) Restore(name string) error {
+	image, ok := c.checkpoints[name]
+	if !ok {
+		return fmt.Errorf("unknown checkpoint %s", name)
+	}
+	l := c.pool.log()
+	if err := forceRemove(l, c.ContainerID); err != nil {
+		return fmt.Errorf("Error removing container %s: %v", c.ContainerID, err)
+	}
+	id, err := c.options.run(l, c.name, image, c.ports)
+	if err != nil {
+		return err
+	}
+	c.ContainerID = ContainerID(id)
+	return c.awaitRestart()
+}
+
+// removeCheckpoints removes the images created by Checkpoint.
+func (c *Container) removeCheckpoints() error {
+	if Debug || len(c.checkpoints) == 0 {
+		return nil
+	}
+	args := []string{"rmi"}
+	for _, image := range c.checkpoints {
+		args = append(args, image)
+	}
+	c.checkpoints = nil
+	return runDockerCommand(c.pool.log(), "docker", args...).Run()
+}
+
+func shortID(c ContainerID) string {
+	if len(c) > 12 {
+		return string(c[:12])
+	}
+	return string(c)
+}
+
+// checkpointDatabase returns the name of the database holding the checkpoint name of database.
+func checkpointDatabase(database, name string) string {
+	return "checkpoint_" + nonIdentifier.ReplaceAllString(strings.ToLower(database+"_"+name), "_")
+}
+
+// Checkpoint copies the database PostgresUsername into a database that serves as template for Restore.
+func (c *PostgreSQLContainer) Checkpoint(name string) error {
+	return c.CheckpointDatabase(PostgresUsername, name)
+}
+
+// Restore recreates the database PostgresUsername from the checkpoint name.
+func (c *PostgreSQLContainer) Restore(name string) error {
+	return c.RestoreDatabase(PostgresUsername, name)
+}
+
+// CheckpointDatabase copies database into a database that serves as template for RestoreDatabase.
+// All connections to database are terminated, because PostgreSQL only copies unused databases.
+func (c *PostgreSQLContainer) CheckpointDatabase(database, name string) error {
+	return c.copyDatabase(database, checkpointDatabase(database, name))
+}
+
+// RestoreDatabase terminates all connections to database and recreates it from the checkpoint name.
+func (c *PostgreSQLContainer) RestoreDatabase(database, name string) error {
+	cp := checkpointDatabase(database, name)
+	exists, err := c.Query(maintenanceDatabase(database), fmt.Sprintf(`SELECT 1 FROM pg_database WHERE datname = '%s'`, cp))
+	if err != nil {
+		return err
+	}
+	if exists != "1" {
+		return fmt.Errorf("unknown checkpoint %s of database %s", name, database)
+	}
+	return c.copyDatabase(cp, database)
+}
+
+// copyDatabase replaces dst with a copy of src.
+func (c *PostgreSQLContainer) copyDatabase(src, dst string) error {
+	maint := maintenanceDatabase(src, dst)
+	_, err := c.Query(maint, fmt.Sprintf(`SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname IN ('%s', '%s')`, src, dst))
+	if err != nil {
+		return err
+	}
+	if _, err := c.Query(maint, fmt.Sprintf(`DROP DATABASE IF EXISTS "%s"`, dst)); err != nil {
+		return err
+	}
+	_, err = c.Query(maint, fmt.Sprintf(`CREATE DATABASE "%s" TEMPLATE "%s"`, dst, src))
+	return err
+}
+
+// maintenanceDatabase returns a database psql can connect to while the given databases are dropped or copied.
+func maintenanceDatabase(databases ...string) string {
+	for _, db := range databases {
+		if db == PostgresUsername {
+			return "template1"
+		}
+	}
+	return PostgresUsername
+}
+
+// mysqlSystemDatabases are left alone by MySQL checkpoints.
+const mysqlSystemDatabases = "'mysql', 'information_schema', 'performance_schema', 'sys'"
+
+// mysqlShellClient and mysqlDump find the client programs in shell scripts. Recent MariaDB
+// images only ship them under their mariadb names.
+const (
+	mysqlShellClient = "$(command -v mysql || command -v mariadb)"
+	mysqlDump        = "$(command -v mysqldump || command -v mariadb-dump)"
+)
+
+// mysqlCheckpointFile returns the path of the dump holding the checkpoint name inside the container.
+func mysqlCheckpointFile(name string) string {
+	return "/tmp/dockertest-checkpoint-" + nonIdentifier.ReplaceAllString(strings.ToLower(name), "_") + ".sql"
+}
+
+// userDatabases returns the names of all non-system databases.
+func (c *MySQLContainer) userDatabases() ([]string, error) {
+	out, err := c.Query("SELECT schema_name FROM information_schema.schemata WHERE schema_name NOT IN (" + mysqlSystemDatabases + ")")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// Checkpoint dumps all non-system databases with mysqldump to a file inside the container.
+func (c *MySQLContainer) Checkpoint(name string) error {
+	dbs, err := c.userDatabases()
+	if err != nil {
+		return err
+	}
+	file := mysqlCheckpointFile(name)
+	script := ": > " + file
+	if len(dbs) > 0 {
+		script = fmt.Sprintf(mysqlDump+" -u %s --add-drop-database --routines --events --databases %s > %s", MySQLUsername, strings.Join(dbs, " "), file)
+	}
+	_, err = c.ContainerID.exec(c.pool.log(), []string{"MYSQL_PWD=" + MySQLPassword}, "sh", "-c", script)
+	return err
+}
+
+// Restore drops all non-system databases and loads the dump of the checkpoint name.
+func (c *MySQLContainer) Restore(name string) error {
+	file := mysqlCheckpointFile(name)
+	if _, err := c.Exec("test", "-f", file); err != nil {
+		return fmt.Errorf("unknown checkpoint %s", name)
+	}
+	dbs, err := c.userDatabases()
+	if err != nil {
+		return err
+	}
+	for _, db := range dbs {
+		if err := c.DropDatabase(db); err != nil {
+			return err
+		}
+	}
+	_, err = c.ContainerID.exec(c.pool.log(), []string{"MYSQL_PWD=" + MySQLPassword}, "sh", "-c", fmt.Sprintf(mysqlShellClient+" -u %s < %s", MySQLUsername, file))
+	return err
+}
+
+// redisCheckpointFile returns the path of the RDB file holding the checkpoint name inside the container.
+func redisCheckpointFile(name string) string {
+	return "/data/dockertest-checkpoint-" + nonIdentifier.ReplaceAllString(strings.ToLower(name), "_") + ".rdb"
+}
+
+// Checkpoint saves the data set with SAVE and keeps a copy of the RDB file.
+func (c *RedisContainer) Checkpoint(name string) error {
+	if _, err := c.CLI("SAVE"); err != nil {
+		return err
+	}
+	_, err := c.Exec("cp", "/data/dump.rdb", redisCheckpointFile(name))
+	return err
+}
+
+// Restore flushes the data set and loads the RDB file of the checkpoint name. Redis only loads
+// RDB files at startup, so the container is restarted with saving disabled, which keeps its host port.
+func (c *RedisContainer) Restore(name string) error {
+	file := redisCheckpointFile(name)
+	if _, err := c.Exec("test", "-f", file); err != nil {
+		return fmt.Errorf("unknown checkpoint %s", name)
+	}
+	if _, err := c.CLI("FLUSHALL"); err != nil {
+		return err
+	}
+	// Without save points Redis does not overwrite dump.rdb when it shuts down.
+	if _, err := c.CLI("CONFIG", "SET", "save", ""); err != nil {
+		return err
+	}
+	if _, err := c.Exec("cp", file, "/data/dump.rdb"); err != nil {
+		return err
+	}
+	return c.Restart()
+}
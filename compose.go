@@ -0,0 +1,176 @@
+package dockertest
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Compose runs a docker compose project as a test fixture.
+type Compose struct {
+	// Project is the compose project name, which prefixes container and network names.
+	Project string
+	// Files are the compose files, passed with -f in order.
+	Files []string
+	// Env overrides environment variables used for variable substitution in the compose files.
+	Env map[string]string
+	// MaxWait is how long to wait for each service to become ready. Defaults to 60 seconds.
+	MaxWait time.Duration
+
+	pool  *Pool
+	waits []composeWait
+}
+
+// composeWait declares when a service of a compose project is ready.
+type composeWait struct {
+	service string
+	port    int
+	wait    WaitStrategy
+}
+
+// Compose returns a compose project run with the pool's logger. Nothing is started before Up.
+func (p *Pool) Compose(project string, files ...string) *Compose {
+	return &Compose{Project: project, Files: files, Env: map[string]string{}, MaxWait: 60 * time.Second, pool: p}
+}
+
+// NewCompose returns a compose project using the default pool, see Pool.Compose.
+func NewCompose(project string, files ...string) *Compose {
+	return defaultPool.Compose(project, files...)
+}
+
+// WaitFor makes Up wait until port of service accepts TCP connections and then for s, which may be nil.
+func (c *Compose) WaitFor(service string, port int, s WaitStrategy) *Compose {
+	c.waits = append(c.waits, composeWait{service: service, port: port, wait: s})
+	return c
+}
+
+// composeCommand returns the command and the leading arguments to run compose with.
+func composeCommand() (string, []string) {
+	if ComposeCommand != "" {
+		f := strings.Fields(ComposeCommand)
+		return f[0], f[1:]
+	}
+	if _, err := exec.LookPath("docker-compose"); err == nil {
+		return "docker-compose", nil
+	}
+	return "docker", []string{"compose"}
+}
+
+// command returns a compose command for the project with the given arguments.
+func (c *Compose) command(args ...string) *dockerCmd {
+	name, all := composeCommand()
+	all = append(all, "-p", c.Project)
+	for _, f := range c.Files {
+		all = append(all, "-f", f)
+	}
+	cmd := localCommand(c.pool.log(), name, append(all, args...)...)
+	cmd.Env = os.Environ()
+	keys := make([]string, 0, len(c.Env))
+	for k := range c.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cmd.Env = append(cmd.Env, k+"="+c.Env[k])
+	}
+	return cmd
+}
+
+// output runs a compose command and returns its trimmed standard output.
+func (c *Compose) output(args ...string) (string, error) {
+	out, err := c.command(args...).Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("Error running compose %s: %v: %s", strings.Join(args, " "), err, ee.Stderr)
+		}
+		return "", fmt.Errorf("Error running compose %s: %v", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Up starts the project in the background and waits until the services given to WaitFor are ready.
+// If a service does not become ready, the project is taken down again.
+func (c *Compose) Up() error {
+	if c.Project == "" {
+		return fmt.Errorf("compose project name must not be empty")
+	}
+	c.pool.log().Infof("compose up %s", c.Project)
+	if _, err := c.output("up", "-d"); err != nil {
+		c.Down()
+		return err
+	}
+	for _, w := range c.waits {
+		con, err := c.Container(w.service, w.port)
+		if err == nil && w.wait != nil {
+			err = w.wait.WaitUntilReady(con, c.MaxWait)
+		}
+		if err != nil {
+			c.Down()
+			return fmt.Errorf("compose service %s is not ready: %v", w.service, err)
+		}
+	}
+	return nil
+}
+
+// Container returns the container of service, with port as its published port, once that port
+// is reachable. The container must not be removed with KillRemove; use Down instead.
+func (c *Compose) Container(service string, port int) (*Container, error) {
+	l := c.pool.log()
+	id, err := c.output("ps", "-q", service)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, fmt.Errorf("compose service %s is not running", service)
+	}
+	id = strings.SplitN(id, "\n", 2)[0]
+	out, err := c.output("port", service, strconv.Itoa(port))
+	if err != nil {
+		return nil, err
+	}
+	_, hostPort, err := net.SplitHostPort(strings.SplitN(out, "\n", 2)[0])
+	if err != nil {
+		return nil, fmt.Errorf("port %d of compose service %s is not published: %q", port, service, out)
+	}
+	p, err := strconv.Atoi(hostPort)
+	if err != nil {
+		return nil, err
+	}
+	host, err := ContainerID(id).lookup(l, p, c.MaxWait)
+	if err != nil {
+		return nil, err
+	}
+	return &Container{
+		ContainerID:   ContainerID(id),
+		Host:          host,
+		Port:          p,
+		ContainerPort: port,
+		ports:         map[int]int{port: p},
+		pool:          c.pool,
+		options:       &RunOptions{MaxWait: c.MaxWait},
+	}, nil
+}
+
+// Addr returns the host:port address port of service is published on.
+func (c *Compose) Addr(service string, port int) (string, error) {
+	con, err := c.Container(service, port)
+	if err != nil {
+		return "", err
+	}
+	return con.Addr(), nil
+}
+
+// Down stops the project and removes its containers, networks and volumes.
+func (c *Compose) Down() error {
+	if Debug {
+		return nil
+	}
+	c.pool.log().Infof("compose down %s", c.Project)
+	_, err := c.output("down", "-v", "--remove-orphans")
+	return err
+}
@@ -0,0 +1,78 @@
+package dockertest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCompose writes a compose command that records its arguments and publishes port
+// for every service.
+func fakeCompose(t *testing.T, port int) (cmd, log string) {
+	dir := t.TempDir()
+	log = filepath.Join(dir, "log")
+	cmd = filepath.Join(dir, "docker-compose")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$DB_VERSION $*" >> %s
+case "$*" in
+*" ps "*) echo abc123 ;;
+*" port "*) echo 0.0.0.0:%d ;;
+esac
+`, log, port)
+	if err := ioutil.WriteFile(cmd, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return cmd, log
+}
+
+func TestCompose(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	cmd, log := fakeCompose(t, port)
+	defer func(c, b string) { ComposeCommand, BindDockerToLocalhost = c, b }(ComposeCommand, BindDockerToLocalhost)
+	ComposeCommand, BindDockerToLocalhost = cmd, "true"
+
+	c := (&Pool{Logger: TestLogger(t)}).Compose("proj", "a.yml", "b.yml")
+	c.Env["DB_VERSION"] = "9.6"
+	c.MaxWait = time.Second
+	c.WaitFor("db", 5432, nil)
+	if err := c.Up(); err != nil {
+		t.Fatal(err)
+	}
+	con, err := c.Container("db", 5432)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if con.ContainerID != "abc123" || con.Addr() != ln.Addr().String() {
+		t.Errorf("unexpected container %s at %s", con.ContainerID, con.Addr())
+	}
+	if err := c.Down(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := strings.Split(strings.TrimSpace(string(b)), "\n")
+	want := []string{
+		"9.6 -p proj -f a.yml -f b.yml up -d",
+		"9.6 -p proj -f a.yml -f b.yml ps -q db",
+		"9.6 -p proj -f a.yml -f b.yml port db 5432",
+		"9.6 -p proj -f a.yml -f b.yml ps -q db",
+		"9.6 -p proj -f a.yml -f b.yml port db 5432",
+		"9.6 -p proj -f a.yml -f b.yml down -v --remove-orphans",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got calls\n%s\nwant\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -1,10 +1,12 @@
 package dockertest
 
 import (
+	"bytes"
 	"fmt"
 	"net"
-	"os/exec"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,32 +25,146 @@ func (c ContainerID) Kill() error {
 
 // Remove runs "docker rm" on the container
 func (c ContainerID) Remove() error {
+	return c.remove(Log)
+}
+
+func (c ContainerID) remove(l Logger) error {
 	if Debug || c == "nil" {
 		return nil
 	}
-	return runDockerCommand("docker", "rm", "-v", string(c)).Run()
+	return runDockerCommand(l, "docker", "rm", "-v", string(c)).Run()
 }
 
 // KillRemove calls Kill on the container, and then Remove if there was
 // no error.
 func (c ContainerID) KillRemove() error {
-	if err := c.Kill(); err != nil {
+	return c.killRemove(Log)
+}
+
+func (c ContainerID) killRemove(l Logger) error {
+	if err := killContainer(l, string(c)); err != nil {
+		return err
+	}
+	return c.remove(l)
+}
+
+// Container is a container started by a pool. It embeds the ContainerID, so methods like Kill
+// or IP can be called on it directly.
+type Container struct {
+	ContainerID
+
+	// Image is the image the container was started from.
+	Image string
+	// Host is the address the container's published ports are reachable on.
+	Host string
+	// Port is the host port ContainerPort is published on.
+	Port int
+	// ContainerPort is the port the container was asked to expose.
+	ContainerPort int
+
+	name        string
+	ports       map[int]int
+	pool        *Pool
+	options     *RunOptions
+	service     *Service
+	reused      bool
+	removed     bool
+	checkpoints map[string]string
+	// startedAt is the time docker reports the container was restarted at, see startLogs.
+	startedAt string
+
+	// mu guards proxies and Host, which the proxies read while a restart may change it.
+	mu      sync.Mutex
+	proxies map[int]*Proxy
+}
+
+// Addr returns the host:port address of the container's published port.
+func (c *Container) Addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// HostPort returns the host port containerPort is published on, or 0 if it is not published.
+func (c *Container) HostPort(containerPort int) int {
+	return c.ports[containerPort]
+}
+
+// PortAddr returns the host:port address containerPort is published on.
+func (c *Container) PortAddr(containerPort int) string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.HostPort(containerPort)))
+}
+
+// Reused returns true if the container already existed and was reattached in reuse mode.
+func (c *Container) Reused() bool {
+	return c.reused
+}
+
+// KillRemove kills and removes the container. Containers run in reuse mode are left running
+// for the next test run; use ForceKillRemove to remove them. Shared containers are removed
+// once the last process using them called KillRemove.
+func (c *Container) KillRemove() error {
+	if c.removed {
+		return nil
+	}
+	c.closeProxies()
+	if c.options.SharedKey != "" {
+		c.removed = true
+		return c.release()
+	}
+	if c.options.ReuseKey != "" {
+		c.removed = true
+		c.pool.log().Debugf("keeping reusable container %s (%s)", c.ContainerID, c.Image)
+		return nil
+	}
+	return c.ForceKillRemove()
+}
+
+// ForceKillRemove kills and removes the container, even in reuse mode, as well as the images
+// created by Checkpoint.
+func (c *Container) ForceKillRemove() error {
+	c.closeProxies()
+	if err := c.ContainerID.killRemove(c.pool.log()); err != nil {
 		return err
 	}
-	return c.Remove()
+	c.removed = true
+	return c.removeCheckpoints()
+}
+
+// waitUntilReady runs the wait strategy of the container, if it has one.
+func (c *Container) waitUntilReady() error {
+	if c.options.Wait == nil {
+		return nil
+	}
+	if err := c.options.Wait.WaitUntilReady(c, c.options.MaxWait); err != nil {
+		return fmt.Errorf("container %s (%s) is not ready: %v", c.ContainerID, c.Image, err)
+	}
+	return nil
+}
+
+// Exec runs cmd inside the container with "docker exec" and returns its combined output.
+func (c *Container) Exec(cmd ...string) ([]byte, error) {
+	return c.ContainerID.exec(c.pool.log(), nil, cmd...)
+}
+
+// exec runs cmd inside the container with the additional environment variables env.
+func (c ContainerID) exec(l Logger, env []string, cmd ...string) ([]byte, error) {
+	args := []string{"exec"}
+	for _, e := range env {
+		args = append(args, "-e", e)
+	}
+	args = append(args, string(c))
+	out, err := runDockerCommand(l, "docker", append(args, cmd...)...).CombinedOutput()
+	if err != nil {
+		return out, fmt.Errorf("Error running %q in %s: %v: %s", strings.Join(cmd, " "), c, err, bytes.TrimSpace(out))
+	}
+	return out, nil
 }
 
 // lookup retrieves the ip address of the container, and tries to reach
 // before timeout the tcp address at this ip and given port.
-func (c ContainerID) lookup(port int, timeout time.Duration) (ip string, err error) {
-	if DockerMachineAvailable {
-		var out []byte
-		out, err = exec.Command("docker-machine", "ip", DockerMachineName).Output()
-		ip = strings.TrimSpace(string(out))
-	} else if BindDockerToLocalhost != "" {
-		ip = "127.0.0.1"
-	} else {
-		ip, err = c.IP()
+func (c ContainerID) lookup(l Logger, port int, timeout time.Duration) (ip string, err error) {
+	ip, err = dockerHost(l)
+	if err == nil && ip == "" {
+		ip, err = containerIP(l, string(c))
 	}
 	if err != nil {
 		err = fmt.Errorf("error getting IP: %v", err)
@@ -59,6 +175,20 @@ func (c ContainerID) lookup(port int, timeout time.Duration) (ip string, err err
 	return
 }
 
+// dockerHost returns the address the published ports of all containers are reachable on: the
+// IP of the docker-machine VM, or the loopback address if BindDockerToLocalhost is set. It
+// returns "" if the address is the IP of each container.
+func dockerHost(l Logger) (string, error) {
+	if DockerMachineAvailable {
+		out, err := localCommand(l, "docker-machine", "ip", DockerMachineName).Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	if BindDockerToLocalhost != "" {
+		return "127.0.0.1", nil
+	}
+	return "", nil
+}
+
 // From http://camlistore.org/pkg/netutil#AwaitReachable
 
 // AwaitReachable tries to make a TCP connection to addr regularly.
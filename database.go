@@ -0,0 +1,87 @@
+package dockertest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var nonIdentifier = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// uniqueDatabaseName returns a database name derived from the test name that is a valid unquoted
+// identifier in PostgreSQL and MySQL and unique across tests.
+func uniqueDatabaseName(tb testing.TB) string {
+	name := nonIdentifier.ReplaceAllString(strings.ToLower(tb.Name()), "_")
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		tb.Fatalf("Could not generate database name: %v", err)
+	}
+	return strings.Trim("t_"+name, "_") + "_" + hex.EncodeToString(b)
+}
+
+// initScriptsDir is where the MySQL, MariaDB and PostgreSQL images look for init scripts.
+const initScriptsDir = "/docker-entrypoint-initdb.d"
+
+// initScriptExtensions are the extensions of the files the images run as init scripts.
+var initScriptExtensions = []string{".sh", ".sql", ".sql.gz", ".sql.xz", ".sql.zst"}
+
+// WithInitScripts mounts the given .sql and .sh files, or the files of the given directories,
+// as init scripts for MySQL, MariaDB or PostgreSQL. The image runs them in alphabetical order
+// of their names on the first start, after creating the database.
+func WithInitScripts(paths ...string) RunOption {
+	return func(o *RunOptions) error {
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			files := []string{path}
+			if info.IsDir() {
+				if files, err = initScripts(path); err != nil {
+					return err
+				}
+			} else if !isInitScript(path) {
+				return fmt.Errorf("%s is not an init script, expected one of %s", path, strings.Join(initScriptExtensions, ", "))
+			}
+			for _, f := range files {
+				if err := WithBind(f, initScriptsDir+"/"+filepath.Base(f), true)(o); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// initScripts returns the init scripts in dir. Other files are ignored, like the images do.
+func initScripts(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && isInitScript(e.Name()) {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	return files, nil
+}
+
+func isInitScript(path string) bool {
+	for _, ext := range initScriptExtensions {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+package dockertest
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestUniqueDatabaseName(t *testing.T) {
+	t.Run("Weird/Sub-Test Name", func(t *testing.T) {
+		a, b := uniqueDatabaseName(t), uniqueDatabaseName(t)
+		if a == b {
+			t.Errorf("names are not unique: %s", a)
+		}
+		if !regexp.MustCompile(`^t_testuniquedatabasename_weird_sub_[a-z0-9_]*_[0-9a-f]{12}$`).MatchString(a) || len(a) > 63 {
+			t.Errorf("unexpected name %s", a)
+		}
+	})
+}
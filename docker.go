@@ -18,51 +18,129 @@ limitations under the License.
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
 	"math/rand"
 	"regexp"
-
-	"github.com/pborman/uuid"
 )
 
+// dockerMachineOnce detects and starts docker-machine once, as containers may be started
+// in parallel while DockerMachineAvailable is read.
+var dockerMachineOnce sync.Once
+
+// detectDockerMachine sets DockerMachineAvailable and starts the machine on the first call.
+func detectDockerMachine(l Logger) {
+	dockerMachineOnce.Do(func() {
+		DockerMachineAvailable = haveDockerMachine()
+		if DockerMachineAvailable && !startDockerMachine(l) {
+			l.Warnf(`Starting docker machine "%s" failed. This could be because the image is already running or because the image does not exist. Tests will fail if the image does not exist.`, DockerMachineName)
+		}
+	})
+}
+
 /// runLongTest checks all the conditions for running a docker container
 // based on image.
-func runLongTest(image string) error {
-	DockerMachineAvailable = false
-	if haveDockerMachine() {
-		DockerMachineAvailable = true
-		if !startDockerMachine() {
-			log.Printf(`Starting docker machine "%s" failed. This could be because the image is already running or because the image does not exist. Tests will fail if the image does not exist.`, DockerMachineName)
-		}
-	} else if !haveDocker() {
+func runLongTest(l Logger, image string) error {
+	return runLongTestContext(context.Background(), l, image)
+}
+
+// runLongTestContext is runLongTest with a context that cancels pulling the image.
+func runLongTestContext(ctx context.Context, l Logger, image string) error {
+	detectDockerMachine(l)
+	if !DockerMachineAvailable && !haveDocker() {
 		return errors.New("Neither 'docker' nor 'docker-machine' available on this system.")
 	}
-	if ok, err := haveImage(image); !ok || err != nil {
+	if ok, err := haveImage(l, image); !ok || err != nil {
 		if err != nil {
 			return fmt.Errorf("Error checking for docker image %s: %v", image, err)
 		}
-		log.Printf("Pulling docker image %s ...", image)
-		if err := Pull(image); err != nil {
+		l.Infof("Pulling docker image %s ...", image)
+		if err := pullContext(ctx, l, image); err != nil {
 			return fmt.Errorf("Error pulling %s: %v", image, err)
 		}
 	}
 	return nil
 }
 
-func runDockerCommand(command string, args ...string) *exec.Cmd {
+// dockerCmd is an *exec.Cmd that reports its arguments and duration to a logger
+// at debug level once it has run.
+type dockerCmd struct {
+	*exec.Cmd
+	log  Logger
+	args []string
+}
+
+func (c *dockerCmd) logged(start time.Time, err error) {
+	if err != nil {
+		c.log.Debugf("%s (%v, error: %v)", strings.Join(c.args, " "), time.Since(start), err)
+		return
+	}
+	c.log.Debugf("%s (%v)", strings.Join(c.args, " "), time.Since(start))
+}
+
+// Run starts the command and waits for it to complete.
+func (c *dockerCmd) Run() error {
+	start := time.Now()
+	err := c.Cmd.Run()
+	c.logged(start, err)
+	return err
+}
+
+// Output runs the command and returns its standard output.
+func (c *dockerCmd) Output() (out []byte, err error) {
+	start := time.Now()
+	out, err = c.Cmd.Output()
+	c.logged(start, err)
+	return
+}
+
+// CombinedOutput runs the command and returns its combined standard output and standard error.
+func (c *dockerCmd) CombinedOutput() (out []byte, err error) {
+	start := time.Now()
+	out, err = c.Cmd.CombinedOutput()
+	c.logged(start, err)
+	return
+}
+
+func runDockerCommand(l Logger, command string, args ...string) *dockerCmd {
+	return runDockerCommandContext(context.Background(), l, command, args...)
+}
+
+// runDockerCommandContext is runDockerCommand with a context that kills the command when done.
+func runDockerCommandContext(ctx context.Context, l Logger, command string, args ...string) *dockerCmd {
+	all := append([]string{command}, args...)
 	if DockerMachineAvailable {
-		command = "/usr/local/bin/" + strings.Join(append([]string{command}, args...), " ")
-		cmd := exec.Command("docker-machine", "ssh", DockerMachineName, command)
-		return cmd
+		quoted := make([]string, len(all))
+		for i, arg := range all {
+			quoted[i] = shellQuote(arg)
+		}
+		command = "/usr/local/bin/" + strings.Join(quoted, " ")
+		cmd := exec.CommandContext(ctx, "docker-machine", "ssh", DockerMachineName, command)
+		return &dockerCmd{Cmd: cmd, log: l, args: all}
+	}
+	return &dockerCmd{Cmd: exec.CommandContext(ctx, command, args...), log: l, args: all}
+}
+
+// localCommand returns a command run on this machine even if docker-machine is used.
+func localCommand(l Logger, command string, args ...string) *dockerCmd {
+	return &dockerCmd{Cmd: exec.Command(command, args...), log: l, args: append([]string{command}, args...)}
+}
+
+var shellSafe = regexp.MustCompile(`^[a-zA-Z0-9_@%+=:,./-]+$`)
+
+// shellQuote quotes arg for the shell docker-machine ssh runs commands in.
+func shellQuote(arg string) string {
+	if shellSafe.MatchString(arg) {
+		return arg
 	}
-	return exec.Command(command, args...)
+	return "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
 }
 
 // haveDockerMachine returns whether the "docker" command was found.
@@ -72,8 +150,8 @@ func haveDockerMachine() bool {
 }
 
 // startDockerMachine starts the docker machine and returns false if the command failed to execute
-func startDockerMachine() bool {
-	_, err := exec.Command("docker-machine", "start", DockerMachineName).Output()
+func startDockerMachine(l Logger) bool {
+	_, err := localCommand(l, "docker-machine", "start", DockerMachineName).Output()
 	return err == nil
 }
 
@@ -83,18 +161,18 @@ func haveDocker() bool {
 	return err == nil
 }
 
-func haveImage(name string) (ok bool, err error) {
-	out, err := runDockerCommand("docker", "images", "--no-trunc").Output()
+func haveImage(l Logger, name string) (ok bool, err error) {
+	out, err := runDockerCommand(l, "docker", "images", "--no-trunc").Output()
 	if err != nil {
 		return false, err
 	}
 	return bytes.Contains(out, []byte(name)), nil
 }
 
-func run(args ...string) (containerID string, err error) {
+func run(l Logger, args ...string) (containerID string, err error) {
 	var stdout, stderr bytes.Buffer
 	validID := regexp.MustCompile(`^([a-zA-Z0-9]+)$`)
-	cmd := runDockerCommand("docker", append([]string{"run"}, args...)...)
+	cmd := runDockerCommand(l, "docker", append([]string{"run"}, args...)...)
 
 	cmd.Stdout, cmd.Stderr = &stdout, &stderr
 	if err = cmd.Run(); err != nil {
@@ -113,15 +191,27 @@ func run(args ...string) (containerID string, err error) {
 
 // KillContainer runs docker kill on a container.
 func KillContainer(container string) error {
+	return killContainer(Log, container)
+}
+
+func killContainer(l Logger, container string) error {
 	if container != "" {
-		return runDockerCommand("docker", "kill", container).Run()
+		return runDockerCommand(l, "docker", "kill", container).Run()
 	}
 	return nil
 }
 
 // Pull retrieves the docker image with 'docker pull'.
 func Pull(image string) error {
-	out, err := runDockerCommand("docker", "pull", image).CombinedOutput()
+	return pull(Log, image)
+}
+
+func pull(l Logger, image string) error {
+	return pullContext(context.Background(), l, image)
+}
+
+func pullContext(ctx context.Context, l Logger, image string) error {
+	out, err := runDockerCommandContext(ctx, l, "docker", "pull", image).CombinedOutput()
 	if err != nil {
 		err = fmt.Errorf("%v: %s", err, out)
 	}
@@ -130,12 +220,17 @@ func Pull(image string) error {
 
 // IP returns the IP address of the container.
 func IP(containerID string) (string, error) {
-	out, err := runDockerCommand("docker", "inspect", containerID).Output()
+	return containerIP(Log, containerID)
+}
+
+func containerIP(l Logger, containerID string) (string, error) {
+	out, err := runDockerCommand(l, "docker", "inspect", containerID).Output()
 	if err != nil {
 		return "", err
 	}
 	type networkSettings struct {
 		IPAddress string
+		Networks  map[string]struct{ IPAddress string }
 	}
 	type container struct {
 		NetworkSettings networkSettings
@@ -150,6 +245,12 @@ func IP(containerID string) (string, error) {
 	if ip := c[0].NetworkSettings.IPAddress; ip != "" {
 		return ip, nil
 	}
+	// Containers on user defined networks only have an address per network.
+	for _, n := range c[0].NetworkSettings.Networks {
+		if n.IPAddress != "" {
+			return n.IPAddress, nil
+		}
+	}
 	return "", errors.New("could not find an IP. Not running?")
 }
 
@@ -157,8 +258,8 @@ func IP(containerID string) (string, error) {
 // It also looks up the IP address of the container, and tests this address with the given
 // port and timeout. It returns the container ID and its IP address, or makes the test
 // fail on error.
-func setupContainer(image string, port int, timeout time.Duration, start func() (string, error)) (c ContainerID, ip string, err error) {
-	err = runLongTest(image)
+func setupContainer(l Logger, image string, port int, timeout time.Duration, start func() (string, error)) (c ContainerID, ip string, err error) {
+	err = runLongTest(l, image)
 	if err != nil {
 		return "", "", err
 	}
@@ -169,64 +270,78 @@ func setupContainer(image string, port int, timeout time.Duration, start func()
 	}
 
 	c = ContainerID(containerID)
-	ip, err = c.lookup(port, timeout)
+	ip, err = c.lookup(l, port, timeout)
 	if err != nil {
-		c.KillRemove()
+		c.killRemove(l)
 		return "", "", err
 	}
 	return c, ip, nil
 }
 
+// portRand chooses random host ports. It is seeded once: seeding with the clock on every call
+// returns the same number to parallel calls within the clock's resolution.
+var portRand = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
 func randInt(min int, max int) int {
-	rand.Seed(time.Now().UTC().UnixNano())
-	return min + rand.Intn(max-min)
+	portRand.Lock()
+	defer portRand.Unlock()
+	return min + portRand.Intn(max-min)
 }
 
 // SetupMongoContainer sets up a real MongoDB instance for testing purposes,
 // using a Docker container. It returns the container ID and its IP address,
 // or makes the test fail on error.
 func SetupMongoContainer(args ...string) (c ContainerID, ip string, port int, err error) {
-	return SetupContainer(mongoImage, 27017, args...)
+	return setupService("mongo", args...)
 }
 
 // SetupMySQLContainer sets up a real MySQL instance for testing purposes,
 // using a Docker container. It returns the container ID and its IP address,
 // or makes the test fail on error.
 func SetupMySQLContainer(args ...string) (c ContainerID, ip string, port int, err error) {
-	return SetupContainerWithEnv(mysqlImage, 3306, fmt.Sprintf("MYSQL_ROOT_PASSWORD=%s", MySQLPassword), args...)
+	return setupService("mysql", args...)
 }
 
 // SetupPostgreSQLContainer sets up a real PostgreSQL instance for testing purposes,
 // using a Docker container. It returns the container ID and its IP address,
 // or makes the test fail on error.
 func SetupPostgreSQLContainer(args ...string) (c ContainerID, ip string, port int, err error) {
-	return SetupContainerWithEnv(postgresImage, 5432, fmt.Sprintf("POSTGRES_PASSWORD=%s", PostgresPassword), args...)
+	return setupService("postgres", args...)
 }
 
 // SetupElasticSearchContainer sets up a real ElasticSearch instance for testing purposes
-// using a Docker container. It returns the container ID and its IP address,
-// or makes the test fail on error.
+// using a Docker container. It runs the elasticsearch service, whose image is tagged 8.13.4,
+// rather than the untagged image earlier versions ran. Security is disabled, so no credentials
+// are needed and the Username and Password of the service do not apply. It returns
+// the container ID and its IP address, or makes the test fail on error.
 func SetupElasticSearchContainer() (c ContainerID, ip string, port int, err error) {
-	return SetupContainer(elasticsearchImage, 9200)
+	con, err := RunService("elasticsearch", WithoutElasticsearchSecurity())
+	if err != nil {
+		return "", "", 0, err
+	}
+	return con.ContainerID, con.Host, con.Port, nil
 }
 
 // SetupRedisContainer sets up a real Redis instance for testing purposes
 // using a Docker container. It returns the container ID and its IP address,
 // or makes the test fail on error.
 func SetupRedisContainer() (c ContainerID, ip string, port int, err error) {
-	return SetupContainer(redisImage, 6379)
+	return setupService("redis")
 }
 
 // SetupNatsContainer sets up a real natsd instance for testing purposes
 // using Docker container.
 func SetupNatsContainer() (c ContainerID, ip string, port int, err error) {
-	return SetupContainer(natsImage, 4222)
+	return setupService("nats")
 }
 
-// SetupFluentdContainer sets up a real natsd instance for testing purposes
+// SetupFluentdContainer sets up a real fluentd instance for testing purposes
 // using Docker container.
 func SetupFluentdContainer() (c ContainerID, ip string, port int, err error) {
-	return SetupContainer(fluentdImage, 24224)
+	return setupService("fluentd")
 }
 
 // SetupContainer runs docker instance and returns port.
@@ -236,21 +351,5 @@ func SetupContainer(image string, containerPort int, args ...string) (c Containe
 
 // SetupContainerWithEnv runs docker instance with env variable and returns port.
 func SetupContainerWithEnv(image string, containerPort int, env string, args ...string) (c ContainerID, ip string, port int, err error) {
-	log.Printf("setup container %s", image)
-	port = randInt(1024, 49150)
-	forward := fmt.Sprintf("%d:%d", port, containerPort)
-	if BindDockerToLocalhost != "" {
-		forward = "127.0.0.1:" + forward
-	}
-	c, ip, err = setupContainer(image, port, 60*time.Second, func() (string, error) {
-
-		rargs := []string{"--name", uuid.New(), "-d", "-P", "-p", forward}
-		if env != "" {
-			rargs = append(rargs, "-e", env)
-		}
-		rargs = append(rargs, image)
-		rargs = append(rargs, args...)
-		return run(rargs...)
-	})
-	return
+	return defaultPool.SetupContainerWithEnv(image, containerPort, env, args...)
 }
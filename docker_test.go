@@ -1,8 +1,13 @@
 package dockertest
 
 import (
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestMySQLContainer(t *testing.T) {
@@ -58,3 +63,255 @@ func TestContainerWithArgs(t *testing.T) {
 	defer con.KillRemove()
 	log.Printf("%s:%d", ip, port)
 }
+
+func TestPostgreSQLNewDatabase(t *testing.T) {
+	c, err := RunPostgreSQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.KillRemove()
+	if err := c.PrepareTemplate("migrated", func(dsn string) error {
+		_, err := c.Query("migrated", "CREATE TABLE foo (id int)")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("copy", func(t *testing.T) {
+		dsn := c.NewDatabaseFromTemplate(t, "migrated")
+		log.Print(dsn)
+	})
+}
+
+func TestRedisCheckpoint(t *testing.T) {
+	c, err := RunRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.KillRemove()
+	if _, err := c.CLI("SET", "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Checkpoint("initial"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CLI("SET", "foo", "baz"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Restore("initial"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.CLI("GET", "foo"); err != nil || v != "bar" {
+		t.Fatalf("expected bar after restore, got %q (%v)", v, err)
+	}
+}
+
+func TestContainersOnNetwork(t *testing.T) {
+	pool := &Pool{}
+	defer pool.Purge()
+	n, err := pool.CreateNetwork("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	redis, err := pool.Run(redisImage, 6379, WithNetwork(n, "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := redis.NetworkAddr(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Printf("%s in network, %s on host", addr, redis.Addr())
+}
+
+func TestStack(t *testing.T) {
+	s := NewStack(nil).
+		Add("db", StackService{Image: postgresImage, Port: 5432, Options: []RunOption{
+			WithEnv("POSTGRES_PASSWORD=" + PostgresPassword),
+			WithWait(WaitForLog("database system is ready to accept connections", 2)),
+		}}).
+		Add("cache", StackService{Image: redisImage, Port: 6379}).
+		Add("queue", StackService{Image: natsImage, Port: 4222, DependsOn: []string{"db", "cache"}})
+	if err := s.Up(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Down()
+	log.Print(s.Endpoints())
+}
+
+func TestContainerRestart(t *testing.T) {
+	c, err := RunContainer(redisImage, 6379)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.KillRemove()
+	addr := c.Addr()
+	if err := c.Stop(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Addr() != addr {
+		t.Errorf("address changed from %s to %s", addr, c.Addr())
+	}
+}
+
+func TestMongoReplicaSet(t *testing.T) {
+	m, err := RunMongoReplicaSet("rs0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.KillRemove()
+	out, err := m.Eval("rs.status().members[0].stateStr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "PRIMARY" {
+		t.Errorf("expected PRIMARY, got %q", out)
+	}
+	log.Printf("mongo replica set at %s", m.URI())
+}
+
+func TestRedisCluster(t *testing.T) {
+	c, err := RunRedisCluster(3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.KillRemove()
+	if _, err := c.NodeCLI(0, "-c", "set", "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	log.Printf("redis cluster at %v", c.Addrs())
+}
+
+func TestRedisSentinel(t *testing.T) {
+	c, err := RunRedisSentinel("mymaster", 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.KillRemove()
+	addr, err := c.MasterAddr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Printf("redis master at %s, sentinels at %v", addr, c.SentinelAddrs())
+}
+
+func TestMinIO(t *testing.T) {
+	c, err := RunMinIO(WithMinIOCredentials("dockertest", "dockertest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.KillRemove()
+	if err := c.CreateBucket("uploads"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.PutObject("uploads", "dir/hello.txt", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	log.Printf("minio at %s", c.Endpoint())
+}
+
+func TestRabbitMQ(t *testing.T) {
+	c, err := RunRabbitMQ()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.KillRemove()
+	if err := c.CreateVHost("orders"); err != nil {
+		t.Fatal(err)
+	}
+	log.Printf("rabbitmq at %s, management at %s", c.AMQPURL("orders"), c.ManagementURL())
+}
+
+func TestElasticsearch(t *testing.T) {
+	c, err := RunElasticsearch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.KillRemove()
+	if err := c.CreateIndex("books", `{"properties": {"title": {"type": "text"}}}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Refresh("books"); err != nil {
+		t.Fatal(err)
+	}
+	log.Printf("elasticsearch at %s", c.URL())
+}
+
+func TestFluentdCapture(t *testing.T) {
+	c, err := RunFluentd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.KillRemove()
+	if _, err := c.Exec("sh", "-c", `echo '{"msg":"hello"}' | fluent-cat test.tag`); err != nil {
+		t.Fatal(err)
+	}
+	events, err := c.WaitForEvents(1, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events[0].Tag != "test.tag" || events[0].Record["msg"] != "hello" {
+		t.Errorf("unexpected event %+v", events[0])
+	}
+}
+
+func TestNATSJetStream(t *testing.T) {
+	c, err := RunNATS(WithJetStream(), WithNATSToken("dockertest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.KillRemove()
+	log.Printf("nats at %s, monitoring at %s", c.URL(), c.MonitorURL())
+}
+
+func TestNATSCluster(t *testing.T) {
+	c, err := RunNATSCluster(WithJetStream())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.KillRemove()
+	log.Printf("nats cluster at %v", c.URLs())
+}
+
+func TestMariaDBInitScripts(t *testing.T) {
+	dir, err := ioutil.TempDir(".", "initdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "schema.sql"), []byte("CREATE TABLE items (id int);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := RunMariaDB(WithMySQLDatabase("app"), WithMySQLUser("app", "app"), WithInitScripts(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.KillRemove()
+	out, err := c.Query("SHOW TABLES FROM app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "items" {
+		t.Errorf("expected table items, got %q", out)
+	}
+}
+
+func TestParallelStartChecks(t *testing.T) {
+	fakeDocker(t, `echo nats`)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := runLongTest(NopLogger, "nats"); err != nil {
+				t.Error(err)
+			}
+			if port := randInt(1024, 49150); port < 1024 || port >= 49150 {
+				t.Errorf("port %d out of range", port)
+			}
+		}()
+	}
+	wg.Wait()
+}
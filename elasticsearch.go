@@ -0,0 +1,223 @@
+package dockertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// elasticsearchTag is the default tag. The image has no latest tag.
+	elasticsearchTag  = "8.13.4"
+	elasticsearchPort = 9200
+	// elasticsearchHeap is the default heap size, enough for small test indices.
+	elasticsearchHeap = "512m"
+)
+
+func init() {
+	MustRegister(&Service{
+		Name:  "elasticsearch",
+		Image: elasticsearchImage,
+		Tag:   elasticsearchTag,
+		Ports: []int{elasticsearchPort},
+		Env: []string{
+			// A single node cluster skips the bootstrap checks meant for production clusters.
+			"discovery.type=single-node",
+			"ES_JAVA_OPTS=-Xms" + elasticsearchHeap + " -Xmx" + elasticsearchHeap,
+			"ELASTIC_PASSWORD=" + ElasticsearchPassword,
+			// Security still requires credentials, but without TLS clients need no certificate.
+			"xpack.security.http.ssl.enabled=false",
+		},
+		Wait: WaitForClusterHealth("yellow"),
+		// The credentials do not apply to containers run with WithoutElasticsearchSecurity,
+		// such as the one of SetupElasticSearchContainer.
+		Username:         ElasticsearchUsername,
+		Password:         ElasticsearchPassword,
+		ConnectionString: func(c *Container) string { return "http://" + c.Addr() },
+	})
+}
+
+// ElasticsearchContainer is a container running a single Elasticsearch node.
+type ElasticsearchContainer struct {
+	*Container
+}
+
+// RunElasticsearch runs an Elasticsearch container with the default pool, see Pool.RunElasticsearch.
+func RunElasticsearch(opts ...RunOption) (*ElasticsearchContainer, error) {
+	return defaultPool.RunElasticsearch(opts...)
+}
+
+// RunElasticsearch runs a single node Elasticsearch cluster and returns once its health is at
+// least yellow. Security is enabled with the user ElasticsearchUsername and the password
+// ElasticsearchPassword unless disabled with WithoutElasticsearchSecurity.
+func (p *Pool) RunElasticsearch(opts ...RunOption) (*ElasticsearchContainer, error) {
+	c, err := p.RunService("elasticsearch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ElasticsearchContainer{c}, nil
+}
+
+// WithoutElasticsearchSecurity disables authentication, so that clients connect without credentials.
+func WithoutElasticsearchSecurity() RunOption {
+	return WithEnv("xpack.security.enabled=false")
+}
+
+// WithElasticsearchHeap sets the JVM heap size, such as "1g". It defaults to 512m.
+func WithElasticsearchHeap(size string) RunOption {
+	return func(o *RunOptions) error {
+		if size == "" {
+			return fmt.Errorf("heap size must not be empty")
+		}
+		o.Env = append(o.Env, "ES_JAVA_OPTS=-Xms"+size+" -Xmx"+size)
+		return nil
+	}
+}
+
+// URL returns the URL clients connect to, such as "http://127.0.0.1:32768".
+func (c *ElasticsearchContainer) URL() string {
+	return "http://" + c.Addr()
+}
+
+// Username returns the user clients authenticate as, or "" if security is disabled.
+func (c *ElasticsearchContainer) Username() string {
+	if !c.security() {
+		return ""
+	}
+	return ElasticsearchUsername
+}
+
+// Password returns the password clients authenticate with, or "" if security is disabled.
+func (c *ElasticsearchContainer) Password() string {
+	if !c.security() {
+		return ""
+	}
+	return envValue(c.options.Env, "ELASTIC_PASSWORD")
+}
+
+func (c *ElasticsearchContainer) security() bool {
+	return envValue(c.options.Env, "xpack.security.enabled") != "false"
+}
+
+// CreateIndex creates the index name. If mapping is not empty, it is used as the JSON
+// mappings of the index, such as `{"properties": {"title": {"type": "text"}}}`.
+func (c *ElasticsearchContainer) CreateIndex(name, mapping string) error {
+	var body []byte
+	if mapping != "" {
+		body = []byte(`{"mappings": ` + mapping + `}`)
+	}
+	_, err := c.api("PUT", "/"+url.PathEscape(name), "application/json", body)
+	return err
+}
+
+// DeleteIndex deletes the index name.
+func (c *ElasticsearchContainer) DeleteIndex(name string) error {
+	_, err := c.api("DELETE", "/"+url.PathEscape(name), "", nil)
+	return err
+}
+
+// BulkLoad sends the NDJSON file at path to the bulk API. The file holds pairs of action and
+// document lines, such as {"index": {"_index": "books"}} followed by the book. It fails if any
+// action failed. Call Refresh to make the documents visible to searches.
+func (c *ElasticsearchContainer) BulkLoad(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	// The bulk API requires the last line to end with a newline.
+	if len(b) > 0 && b[len(b)-1] != '\n' {
+		b = append(b, '\n')
+	}
+	out, err := c.api("POST", "/_bulk", "application/x-ndjson", b)
+	if err != nil {
+		return err
+	}
+	return bulkError(out)
+}
+
+// Refresh makes all operations performed on the indices visible to searches. Without indices,
+// all indices are refreshed.
+func (c *ElasticsearchContainer) Refresh(indices ...string) error {
+	path := "/_refresh"
+	if len(indices) > 0 {
+		escaped := make([]string, len(indices))
+		for i, index := range indices {
+			escaped[i] = url.PathEscape(index)
+		}
+		path = "/" + strings.Join(escaped, ",") + path
+	}
+	_, err := c.api("POST", path, "", nil)
+	return err
+}
+
+// bulkError returns an error describing the first failed action of a bulk response.
+func bulkError(resp []byte) error {
+	var r struct {
+		Errors bool                                         `json:"errors"`
+		Items  []map[string]struct{ Error json.RawMessage } `json:"items"`
+	}
+	if err := json.Unmarshal(resp, &r); err != nil {
+		return fmt.Errorf("Error parsing bulk response: %v", err)
+	}
+	if !r.Errors {
+		return nil
+	}
+	failed := 0
+	var first json.RawMessage
+	for _, item := range r.Items {
+		for _, result := range item {
+			if len(result.Error) > 0 {
+				if first == nil {
+					first = result.Error
+				}
+				failed++
+			}
+		}
+	}
+	return fmt.Errorf("%d of %d bulk actions failed, first error: %s", failed, len(r.Items), first)
+}
+
+// api sends a request to Elasticsearch and returns the response body.
+func (c *ElasticsearchContainer) api(method, path, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, c.URL()+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.security() {
+		req.SetBasicAuth(c.Username(), c.Password())
+	}
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	out, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("Error requesting %s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(out))
+	}
+	return out, nil
+}
+
+// WaitForClusterHealth waits until the health of the Elasticsearch cluster is at least status,
+// which is "green", "yellow" or "red". Elasticsearch accepts connections before the cluster
+// has formed, so reachability alone is not enough.
+func WaitForClusterHealth(status string) WaitStrategy {
+	return WaitFunc(func(c *Container, timeout time.Duration) error {
+		es := &ElasticsearchContainer{c}
+		return retry(timeout, func() error {
+			_, err := es.api("GET", "/_cluster/health?wait_for_status="+status+"&timeout=1s", "", nil)
+			return err
+		})
+	})
+}
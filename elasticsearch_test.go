@@ -0,0 +1,74 @@
+package dockertest
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBulkError(t *testing.T) {
+	if err := bulkError([]byte(`{"errors": false, "items": [{"index": {"status": 201}}]}`)); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	err := bulkError([]byte(`{"errors": true, "items": [
+		{"index": {"status": 201}},
+		{"index": {"status": 400, "error": {"type": "mapper_parsing_exception"}}},
+		{"create": {"status": 409, "error": {"type": "version_conflict_engine_exception"}}}
+	]}`))
+	if err == nil || !strings.Contains(err.Error(), "2 of 3") || !strings.Contains(err.Error(), "mapper_parsing_exception") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestElasticsearchHelpers(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, strings.Join([]string{r.Method, r.URL.Path, user, pass, r.Header.Get("Content-Type"), string(body)}, " "))
+		if r.URL.Path == "/_bulk" {
+			w.Write([]byte(`{"errors": false, "items": []}`))
+		}
+	}))
+	defer srv.Close()
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	p, _ := strconv.Atoi(port)
+
+	bulk := filepath.Join(t.TempDir(), "bulk.ndjson")
+	if err := ioutil.WriteFile(bulk, []byte(`{"index": {"_index": "books"}}`+"\n"+`{"title": "Go"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		env  []string
+		auth string
+	}{
+		{[]string{"ELASTIC_PASSWORD=secret"}, "elastic secret"},
+		{[]string{"ELASTIC_PASSWORD=secret", "xpack.security.enabled=false"}, " "},
+	} {
+		requests = nil
+		c := &ElasticsearchContainer{&Container{Host: host, Port: p, options: &RunOptions{Env: tc.env}}}
+		if err := c.CreateIndex("books", `{"properties": {"title": {"type": "text"}}}`); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.BulkLoad(bulk); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Refresh("books", "authors"); err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{
+			`PUT /books ` + tc.auth + ` application/json {"mappings": {"properties": {"title": {"type": "text"}}}}`,
+			`POST /_bulk ` + tc.auth + ` application/x-ndjson {"index": {"_index": "books"}}` + "\n" + `{"title": "Go"}` + "\n",
+			`POST /books,authors/_refresh ` + tc.auth + `  `,
+		}
+		if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("expected requests\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(requests, "\n"))
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package dockertest
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// FaultImage is the image of the sidecar containers that inject network faults. It must contain
+// tc and iptables. The sidecar shares the network namespace of the faulty container.
+var FaultImage = "nicolaka/netshoot"
+
+// Netem describes network faults emulated with tc netem on the container's outgoing traffic.
+type Netem struct {
+	// Delay delays every packet.
+	Delay time.Duration
+	// Jitter varies Delay randomly by up to Jitter.
+	Jitter time.Duration
+	// Loss is the percentage of packets dropped, between 0 and 100.
+	Loss float64
+}
+
+// args returns the netem arguments of tc qdisc.
+func (n Netem) args() []string {
+	args := []string{"netem"}
+	if n.Delay > 0 {
+		args = append(args, "delay", fmt.Sprintf("%dus", n.Delay/time.Microsecond))
+		if n.Jitter > 0 {
+			args = append(args, fmt.Sprintf("%dus", n.Jitter/time.Microsecond))
+		}
+	}
+	if n.Loss > 0 {
+		args = append(args, "loss", strconv.FormatFloat(n.Loss, 'f', -1, 64)+"%")
+	}
+	return args
+}
+
+// sidecar runs cmd in a short lived container sharing the network namespace of c.
+func (c *Container) sidecar(cmd ...string) error {
+	l := c.pool.log()
+	if err := runLongTest(l, FaultImage); err != nil {
+		return err
+	}
+	args := []string{"run", "--rm", "--network", "container:" + string(c.ContainerID), "--cap-add", "NET_ADMIN", FaultImage}
+	out, err := runDockerCommand(l, "docker", append(args, cmd...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Error injecting fault into %s: %v: %s", c.ContainerID, err, out)
+	}
+	return nil
+}
+
+// SetNetem emulates delay and packet loss on all traffic the container sends, replacing
+// earlier settings.
+func (c *Container) SetNetem(n Netem) error {
+	if n.Loss < 0 || n.Loss > 100 {
+		return fmt.Errorf("packet loss must be between 0 and 100 percent, got %v", n.Loss)
+	}
+	if n.Delay < 0 || n.Jitter < 0 {
+		return fmt.Errorf("delay and jitter must not be negative")
+	}
+	return c.sidecar(append([]string{"tc", "qdisc", "replace", "dev", "eth0", "root"}, n.args()...)...)
+}
+
+// ClearNetem removes the delay and packet loss set with SetNetem.
+func (c *Container) ClearNetem() error {
+	return c.sidecar("tc", "qdisc", "del", "dev", "eth0", "root")
+}
+
+// BlockPort drops all incoming TCP packets for the container port, so that connections to it hang.
+func (c *Container) BlockPort(port int) error {
+	return c.sidecar("iptables", "-I", "INPUT", "-p", "tcp", "--dport", strconv.Itoa(port), "-j", "DROP")
+}
+
+// UnblockPort lifts a block set with BlockPort.
+func (c *Container) UnblockPort(port int) error {
+	return c.sidecar("iptables", "-D", "INPUT", "-p", "tcp", "--dport", strconv.Itoa(port), "-j", "DROP")
+}
+
+// Disconnect detaches the container from the network n, as if its cable was pulled.
+func (c *Container) Disconnect(n *Network) error {
+	out, err := runDockerCommand(c.pool.log(), "docker", "network", "disconnect", n.Name, string(c.ContainerID)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Error disconnecting %s from network %s: %v: %s", c.ContainerID, n.Name, err, out)
+	}
+	return nil
+}
+
+// Reconnect attaches the container to the network n again, with the aliases it was run with.
+func (c *Container) Reconnect(n *Network) error {
+	var aliases []string
+	for _, a := range c.options.Networks {
+		if a.Network.Name == n.Name {
+			aliases = a.Aliases
+		}
+	}
+	return n.connect(c.ContainerID, aliases)
+}
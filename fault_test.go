@@ -0,0 +1,15 @@
+package dockertest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNetemArgs(t *testing.T) {
+	got := Netem{Delay: 100 * time.Millisecond, Jitter: 10 * time.Millisecond, Loss: 2.5}.args()
+	want := []string{"netem", "delay", "100000us", "10000us", "loss", "2.5%"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,151 @@
+package dockertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	fluentdPort = 24224
+	// fluentdConfig is where the image reads its configuration from.
+	fluentdConfig = "/fluentd/etc/fluent.conf"
+	// fluentdCaptureDir is where the capture configuration writes the received events to.
+	fluentdCaptureDir = "/tmp/capture"
+)
+
+// fluentdCaptureConfig accepts events with the forward protocol and writes each of them as a
+// line of time, tag and JSON record, separated by tabs. The log events fluentd emits about
+// itself, tagged fluent.*, are discarded.
+var fluentdCaptureConfig = []string{
+	"<source>",
+	"  @type forward",
+	fmt.Sprintf("  port %d", fluentdPort),
+	"  bind 0.0.0.0",
+	"</source>",
+	"<match fluent.**>",
+	"  @type null",
+	"</match>",
+	"<match **>",
+	"  @type file",
+	"  path " + fluentdCaptureDir + "/events",
+	"  append true",
+	"  <format>",
+	"    @type out_file",
+	"    time_format %Y-%m-%dT%H:%M:%S.%N%:z",
+	"    utc true",
+	"  </format>",
+	"  <buffer>",
+	"    @type memory",
+	"    flush_mode immediate",
+	"  </buffer>",
+	"</match>",
+}
+
+func init() {
+	MustRegister(&Service{
+		Name:             "fluentd",
+		Image:            fluentdImage,
+		Ports:            []int{fluentdPort},
+		Wait:             WaitForLog("fluentd worker is now running", 1),
+		ConnectionString: func(c *Container) string { return c.Addr() },
+	})
+}
+
+// FluentdEvent is an event received by fluentd.
+type FluentdEvent struct {
+	Tag    string
+	Time   time.Time
+	Record map[string]interface{}
+}
+
+// FluentdContainer is a container running fluentd.
+type FluentdContainer struct {
+	*Container
+}
+
+// RunFluentd runs a fluentd container capturing events with the default pool, see Pool.RunFluentd.
+func RunFluentd(opts ...RunOption) (*FluentdContainer, error) {
+	return defaultPool.RunFluentd(opts...)
+}
+
+// RunFluentd runs fluentd with a configuration that accepts events with the forward protocol
+// on the container's port and writes them to a file, so that Events can return them.
+func (p *Pool) RunFluentd(opts ...RunOption) (*FluentdContainer, error) {
+	script := "mkdir -p " + fluentdCaptureDir + " && " +
+		"printf '%s\\n' " + shellJoin(fluentdCaptureConfig) + " > /tmp/capture.conf && " +
+		"exec fluentd -c /tmp/capture.conf"
+	c, err := p.RunService("fluentd", append([]RunOption{WithCmd("sh", "-c", script)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &FluentdContainer{c}, nil
+}
+
+// RunFluentdWithConfig runs fluentd with a custom configuration with the default pool, see
+// Pool.RunFluentdWithConfig.
+func RunFluentdWithConfig(config string, opts ...RunOption) (*FluentdContainer, error) {
+	return defaultPool.RunFluentdWithConfig(config, opts...)
+}
+
+// RunFluentdWithConfig runs fluentd with the configuration file config mounted as fluent.conf.
+// The configuration should accept events on port 24224. Events only returns what the
+// configuration writes like the capture configuration of RunFluentd does.
+func (p *Pool) RunFluentdWithConfig(config string, opts ...RunOption) (*FluentdContainer, error) {
+	c, err := p.RunService("fluentd", append([]RunOption{WithBind(config, fluentdConfig, true)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &FluentdContainer{c}, nil
+}
+
+// Events returns the events received so far, in the order they were written. Fluentd writes
+// events asynchronously; use WaitForEvents to wait for events that were just sent.
+func (c *FluentdContainer) Events() ([]FluentdEvent, error) {
+	out, err := c.Exec("sh", "-c", "cat "+fluentdCaptureDir+"/* 2>/dev/null || true")
+	if err != nil {
+		return nil, err
+	}
+	return parseFluentdEvents(string(out))
+}
+
+// WaitForEvents waits until at least n events were received and returns them.
+func (c *FluentdContainer) WaitForEvents(n int, timeout time.Duration) ([]FluentdEvent, error) {
+	var events []FluentdEvent
+	err := retry(timeout, func() error {
+		var err error
+		if events, err = c.Events(); err != nil {
+			return err
+		}
+		if len(events) < n {
+			return fmt.Errorf("received %d of %d events", len(events), n)
+		}
+		return nil
+	})
+	return events, err
+}
+
+// parseFluentdEvents parses the lines written by the capture configuration.
+func parseFluentdEvents(out string) ([]FluentdEvent, error) {
+	var events []FluentdEvent
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("Error parsing fluentd event %q", line)
+		}
+		t, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing time of fluentd event %q: %v", line, err)
+		}
+		e := FluentdEvent{Tag: fields[1], Time: t}
+		if err := json.Unmarshal([]byte(fields[2]), &e.Record); err != nil {
+			return nil, fmt.Errorf("Error parsing record of fluentd event %q: %v", line, err)
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
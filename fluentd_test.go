@@ -0,0 +1,47 @@
+package dockertest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFluentdEvents(t *testing.T) {
+	out := "2024-03-01T12:00:00.123456789+00:00\tapp.access\t{\"path\":\"/\",\"status\":200}\n" +
+		"2024-03-01T12:00:01.000000000+00:00\tapp.error\t{\"msg\":\"tab\\tinside\"}\n"
+	events, err := parseFluentdEvents(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if e := events[0]; e.Tag != "app.access" || e.Record["status"] != float64(200) ||
+		!e.Time.Equal(time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC)) {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if e := events[1]; e.Tag != "app.error" || e.Record["msg"] != "tab\tinside" {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	if _, err := parseFluentdEvents("garbage\n"); err == nil {
+		t.Error("expected error for malformed line")
+	}
+	if events, err := parseFluentdEvents(""); err != nil || len(events) != 0 {
+		t.Errorf("expected no events, got %v, %v", events, err)
+	}
+}
+
+func TestFluentdCaptureConfigDiscardsOwnLogs(t *testing.T) {
+	discard, capture := -1, -1
+	for i, line := range fluentdCaptureConfig {
+		switch line {
+		case "<match fluent.**>":
+			discard = i
+		case "<match **>":
+			capture = i
+		}
+	}
+	if discard < 0 || capture < 0 || discard > capture {
+		t.Errorf("fluent.* events are not discarded before the catch-all match: %q", fluentdCaptureConfig)
+	}
+}
@@ -0,0 +1,206 @@
+package dockertest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContainerInfo is the state and configuration of a container as reported by "docker inspect".
+type ContainerInfo struct {
+	// ID is the full id of the container.
+	ID string
+	// Name is the name of the container, without leading slash.
+	Name string
+	// Image is the name of the image the container was created from.
+	Image string
+	// ImageID is the content addressable id of the image.
+	ImageID string
+	// ImageDigests are the repository digests of the image, such as postgres@sha256:...
+	// They are empty for images that were built locally and never pushed or pulled, and nil
+	// if the image could not be inspected.
+	ImageDigests []string
+	State        ContainerState
+	// Ports maps container ports like "5432/tcp" to the host addresses they are published on.
+	Ports map[string][]PortBinding
+	// Networks maps network names to the container's settings in the network.
+	Networks map[string]NetworkInfo
+	// Env holds the KEY=value environment variables of the container.
+	Env []string
+	// Labels are the labels of the container.
+	Labels map[string]string
+	// Mounts are the volumes, bind mounts and tmpfs mounted into the container.
+	Mounts []MountInfo
+}
+
+// ContainerState is the run state of a container.
+type ContainerState struct {
+	// Status is one of "created", "running", "paused", "restarting", "removing", "exited" and "dead".
+	Status     string
+	Running    bool
+	Paused     bool
+	Restarting bool
+	// OOMKilled is true if the kernel killed the container because it ran out of memory.
+	OOMKilled  bool
+	Pid        int
+	ExitCode   int
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// Health is the status of the image's health check: "starting", "healthy" or "unhealthy",
+	// or empty if the image has none.
+	Health string
+}
+
+// PortBinding is a host address a container port is published on.
+type PortBinding struct {
+	HostIP   string
+	HostPort int
+}
+
+// NetworkInfo describes a container's settings in a network.
+type NetworkInfo struct {
+	IPAddress  string
+	Gateway    string
+	MacAddress string
+	Aliases    []string
+}
+
+// MountInfo describes a mount of a container.
+type MountInfo struct {
+	// Type is "bind", "volume" or "tmpfs".
+	Type string
+	// Name is the name of a volume.
+	Name        string
+	Source      string
+	Destination string
+	ReadOnly    bool
+}
+
+// inspectJSON is the part of the output of "docker inspect" that ContainerInfo is built from.
+type inspectJSON struct {
+	ID    string `json:"Id"`
+	Name  string
+	Image string
+	State struct {
+		Status     string
+		Running    bool
+		Paused     bool
+		Restarting bool
+		OOMKilled  bool
+		Pid        int
+		ExitCode   int
+		Error      string
+		StartedAt  time.Time
+		FinishedAt time.Time
+		Health     *struct {
+			Status string
+		}
+	}
+	Config struct {
+		Image  string
+		Env    []string
+		Labels map[string]string
+	}
+	NetworkSettings struct {
+		Ports map[string][]struct {
+			HostIP   string `json:"HostIp"`
+			HostPort string
+		}
+		Networks map[string]NetworkInfo
+	}
+	Mounts []struct {
+		Type        string
+		Name        string
+		Source      string
+		Destination string
+		RW          bool
+	}
+}
+
+// parseInspect builds a ContainerInfo from the output of "docker inspect".
+func parseInspect(out []byte) (*ContainerInfo, error) {
+	var raw []inspectJSON
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf("Error decoding docker inspect: %v", err)
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("no output from docker inspect")
+	}
+	r := raw[0]
+	info := &ContainerInfo{
+		ID:      r.ID,
+		Name:    strings.TrimPrefix(r.Name, "/"),
+		Image:   r.Config.Image,
+		ImageID: r.Image,
+		State: ContainerState{
+			Status:     r.State.Status,
+			Running:    r.State.Running,
+			Paused:     r.State.Paused,
+			Restarting: r.State.Restarting,
+			OOMKilled:  r.State.OOMKilled,
+			Pid:        r.State.Pid,
+			ExitCode:   r.State.ExitCode,
+			Error:      r.State.Error,
+			StartedAt:  r.State.StartedAt,
+			FinishedAt: r.State.FinishedAt,
+		},
+		Ports:    map[string][]PortBinding{},
+		Networks: r.NetworkSettings.Networks,
+		Env:      r.Config.Env,
+		Labels:   r.Config.Labels,
+	}
+	if r.State.Health != nil {
+		info.State.Health = r.State.Health.Status
+	}
+	for port, bindings := range r.NetworkSettings.Ports {
+		info.Ports[port] = []PortBinding{}
+		for _, b := range bindings {
+			hostPort, err := strconv.Atoi(b.HostPort)
+			if err != nil {
+				return nil, fmt.Errorf("invalid host port %q of %s", b.HostPort, port)
+			}
+			info.Ports[port] = append(info.Ports[port], PortBinding{HostIP: b.HostIP, HostPort: hostPort})
+		}
+	}
+	for _, m := range r.Mounts {
+		info.Mounts = append(info.Mounts, MountInfo{Type: m.Type, Name: m.Name, Source: m.Source, Destination: m.Destination, ReadOnly: !m.RW})
+	}
+	return info, nil
+}
+
+// Inspect returns the state and configuration of the container.
+func (c ContainerID) Inspect() (*ContainerInfo, error) {
+	return c.inspect(Log)
+}
+
+// Inspect returns the state and configuration of the container.
+func (c *Container) Inspect() (*ContainerInfo, error) {
+	return c.ContainerID.inspect(c.pool.log())
+}
+
+func (c ContainerID) inspect(l Logger) (*ContainerInfo, error) {
+	out, err := runDockerCommand(l, "docker", "inspect", string(c)).Output()
+	if err != nil {
+		return nil, fmt.Errorf("Error inspecting %s: %v", c, err)
+	}
+	info, err := parseInspect(out)
+	if err != nil {
+		return nil, err
+	}
+	// The digests are informational, so the container can be inspected even if its image was
+	// removed in the meantime.
+	digests, err := runDockerCommand(l, "docker", "image", "inspect", "-f", "{{json .RepoDigests}}", info.ImageID).Output()
+	if err != nil {
+		l.Warnf("could not inspect image %s of %s: %v", info.ImageID, c, err)
+		return info, nil
+	}
+	if err := json.Unmarshal(digests, &info.ImageDigests); err != nil {
+		l.Warnf("could not decode digests of image %s: %v", info.ImageID, err)
+		info.ImageDigests = nil
+	}
+	return info, nil
+}
@@ -0,0 +1,77 @@
+package dockertest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const inspectOutput = `[{
+	"Id": "abc123",
+	"Name": "/pg",
+	"Image": "sha256:feed",
+	"State": {
+		"Status": "exited", "Running": false, "OOMKilled": true, "ExitCode": 137,
+		"StartedAt": "2016-01-02T03:04:05.123456789Z", "FinishedAt": "2016-01-02T03:05:05Z",
+		"Health": {"Status": "unhealthy", "FailingStreak": 3}
+	},
+	"Config": {"Image": "postgres:9.6", "Env": ["POSTGRES_PASSWORD=docker"], "Labels": {"a": "b"}},
+	"NetworkSettings": {
+		"Ports": {"5432/tcp": [{"HostIp": "0.0.0.0", "HostPort": "32768"}], "8080/tcp": null},
+		"Networks": {"bridge": {"IPAddress": "172.17.0.2", "Gateway": "172.17.0.1", "Aliases": null}}
+	},
+	"Mounts": [{"Type": "volume", "Name": "data", "Source": "/var/lib/docker/volumes/data/_data", "Destination": "/data", "RW": false}]
+}]`
+
+func TestParseInspect(t *testing.T) {
+	info, err := parseInspect([]byte(inspectOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ContainerInfo{
+		ID:      "abc123",
+		Name:    "pg",
+		Image:   "postgres:9.6",
+		ImageID: "sha256:feed",
+		State: ContainerState{
+			Status:     "exited",
+			OOMKilled:  true,
+			ExitCode:   137,
+			StartedAt:  time.Date(2016, 1, 2, 3, 4, 5, 123456789, time.UTC),
+			FinishedAt: time.Date(2016, 1, 2, 3, 5, 5, 0, time.UTC),
+			Health:     "unhealthy",
+		},
+		Ports: map[string][]PortBinding{
+			"5432/tcp": {{HostIP: "0.0.0.0", HostPort: 32768}},
+			"8080/tcp": {},
+		},
+		Networks: map[string]NetworkInfo{"bridge": {IPAddress: "172.17.0.2", Gateway: "172.17.0.1"}},
+		Env:      []string{"POSTGRES_PASSWORD=docker"},
+		Labels:   map[string]string{"a": "b"},
+		Mounts:   []MountInfo{{Type: "volume", Name: "data", Source: "/var/lib/docker/volumes/data/_data", Destination: "/data", ReadOnly: true}},
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("got  %+v\nwant %+v", info, want)
+	}
+}
+
+func TestInspectWithoutImage(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "inspect.json")
+	if err := ioutil.WriteFile(out, []byte(inspectOutput), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fakeDocker(t, `case "$1" in
+inspect) cat `+out+` ;;
+image) echo "No such image" >&2; exit 1 ;;
+esac
+`)
+	info, err := ContainerID("abc123").inspect(NopLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ID != "abc123" || info.ImageDigests != nil {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
@@ -0,0 +1,120 @@
+package dockertest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pborman/uuid"
+)
+
+// logTailLines is the number of log lines an ExitError includes.
+const logTailLines = 20
+
+// JobResult is the outcome of a container run to completion.
+type JobResult struct {
+	// ExitCode is the exit code of the container's main process.
+	ExitCode int
+	// Logs are the combined standard output and standard error of the container.
+	Logs []byte
+}
+
+// ExitError is returned by RunToCompletion if the container exited with a non-zero exit code.
+type ExitError struct {
+	Image    string
+	ExitCode int
+	// LogTail holds the last lines the container logged.
+	LogTail string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("container %s exited with code %d:\n%s", e.Image, e.ExitCode, e.LogTail)
+}
+
+// tail returns the last n lines of b.
+func tail(b []byte, n int) string {
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RunToCompletion runs image with the default pool until it exits, see Pool.RunToCompletion.
+func RunToCompletion(ctx context.Context, image string, opts ...RunOption) (*JobResult, error) {
+	return defaultPool.RunToCompletion(ctx, image, opts...)
+}
+
+// RunToCompletion runs a one-shot container, such as a migration or a schema loader, waits
+// until it exits and removes it. No port is published. If the container exits with a non-zero
+// exit code, the result is returned together with an *ExitError. If ctx is done first, the
+// container is killed and ctx.Err() returned, also while the image is pulled. WithReuse,
+// WithShared, WithWait, WithProxy and the restart policies "always" and "unless-stopped" apply
+// to long-running containers only and are rejected.
+func (p *Pool) RunToCompletion(ctx context.Context, image string, opts ...RunOption) (*JobResult, error) {
+	o, err := newRunOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if o.ReuseKey != "" || o.SharedKey != "" || o.Wait != nil || o.Proxy {
+		return nil, fmt.Errorf("invalid run option: WithReuse, WithShared, WithWait and WithProxy do not apply to RunToCompletion")
+	}
+	// docker wait never returns for containers that are restarted whenever they exit.
+	if policy := strings.SplitN(o.RestartPolicy, ":", 2)[0]; policy == "always" || policy == "unless-stopped" {
+		return nil, fmt.Errorf("invalid run option: restart policy %s does not apply to RunToCompletion", policy)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	l := p.log()
+	if err := runLongTestContext(ctx, l, image); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	l.Infof("run to completion %s", image)
+	id, err := o.run(l, uuid.New(), image, nil)
+	if err != nil {
+		return nil, err
+	}
+	c := ContainerID(id)
+	defer c.remove(l)
+
+	type waited struct {
+		out []byte
+		err error
+	}
+	done := make(chan waited, 1)
+	go func() {
+		out, err := runDockerCommand(l, "docker", "wait", id).Output()
+		done <- waited{out, err}
+	}()
+	var w waited
+	select {
+	case w = <-done:
+	case <-ctx.Done():
+		killContainer(l, id)
+		<-done
+		return nil, ctx.Err()
+	}
+	if w.err != nil {
+		return nil, fmt.Errorf("Error waiting for %s: %v", c, w.err)
+	}
+	code, err := strconv.Atoi(string(bytes.TrimSpace(w.out)))
+	if err != nil {
+		return nil, fmt.Errorf("Unexpected output from docker wait: %q", w.out)
+	}
+
+	logs, err := runDockerCommand(l, "docker", "logs", id).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading logs of %s: %v: %s", c, err, logs)
+	}
+	res := &JobResult{ExitCode: code, Logs: logs}
+	if code != 0 {
+		return res, &ExitError{Image: image, ExitCode: code, LogTail: tail(logs, logTailLines)}
+	}
+	return res, nil
+}
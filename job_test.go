@@ -0,0 +1,83 @@
+package dockertest
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDocker puts a docker command on the PATH that runs script for every invocation.
+func fakeDocker(t *testing.T, script string) {
+	fakeCommand(t, "docker", script)
+}
+
+// fakeCommand puts the command name on the PATH that runs script for every invocation.
+func fakeCommand(t *testing.T, name, script string) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	t.Cleanup(func() { os.Setenv("PATH", path) })
+}
+
+func TestRunToCompletionExitError(t *testing.T) {
+	fakeDocker(t, `case "$1" in
+images) echo migrate ;;
+run) echo abc123 ;;
+wait) echo 3 ;;
+logs) for i in $(seq 1 30); do echo "line $i"; done ;;
+esac
+`)
+	res, err := (&Pool{Logger: NopLogger}).RunToCompletion(context.Background(), "migrate", WithCmd("up"))
+	exit, ok := err.(*ExitError)
+	if !ok {
+		t.Fatalf("expected *ExitError, got %v", err)
+	}
+	if res.ExitCode != 3 || exit.ExitCode != 3 {
+		t.Errorf("unexpected exit code %d", res.ExitCode)
+	}
+	if !strings.HasPrefix(exit.LogTail, "line 11\n") || !strings.HasSuffix(exit.LogTail, "line 30") {
+		t.Errorf("unexpected log tail %q", exit.LogTail)
+	}
+}
+
+func TestRunToCompletionRejectsOptions(t *testing.T) {
+	fakeDocker(t, `echo "docker must not be called" >&2; exit 1`)
+	p := &Pool{Logger: NopLogger}
+	for name, opt := range map[string]RunOption{
+		"reuse":  WithReuse("migrate"),
+		"shared": WithShared("migrate"),
+		"wait":   WithWait(WaitForLog("done", 1)),
+		"proxy":  WithProxy(),
+		"always": WithRestartPolicy("always", 0),
+		"unless": WithRestartPolicy("unless-stopped", 0),
+	} {
+		_, err := p.RunToCompletion(context.Background(), "migrate", opt)
+		if err == nil || !strings.HasPrefix(err.Error(), "invalid run option") {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+}
+
+func TestRunToCompletionCancelledPull(t *testing.T) {
+	fakeDocker(t, `case "$1" in
+pull) exec sleep 10 ;;
+esac
+`)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := (&Pool{Logger: NopLogger}).RunToCompletion(ctx, "migrate")
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("pull was not cancelled, returned after %v", d)
+	}
+}
@@ -0,0 +1,203 @@
+package dockertest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	kafkaTag       = "3.7.0"
+	kafkaPort      = 9092
+	kafkaClusterID = "5L6g3nShT-eMCtK--X86sw"
+	// kafkaInternal is the listener the command line tools inside the container connect to.
+	kafkaInternal = "localhost:9094"
+	kafkaBin      = "/opt/kafka/bin/"
+)
+
+func init() {
+	MustRegister(&Service{
+		Name:  "kafka",
+		Image: kafkaImage,
+		Tag:   kafkaTag,
+		Ports: []int{kafkaPort},
+		Env: []string{
+			"CLUSTER_ID=" + kafkaClusterID,
+			"KAFKA_NODE_ID=1",
+			"KAFKA_PROCESS_ROLES=broker,controller",
+			"KAFKA_LISTENERS=PLAINTEXT://0.0.0.0:9092,INTERNAL://0.0.0.0:9094,CONTROLLER://0.0.0.0:9093",
+			"KAFKA_LISTENER_SECURITY_PROTOCOL_MAP=PLAINTEXT:PLAINTEXT,INTERNAL:PLAINTEXT,CONTROLLER:PLAINTEXT",
+			"KAFKA_CONTROLLER_LISTENER_NAMES=CONTROLLER",
+			"KAFKA_INTER_BROKER_LISTENER_NAME=INTERNAL",
+			"KAFKA_CONTROLLER_QUORUM_VOTERS=1@localhost:9093",
+			"KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR=1",
+			"KAFKA_TRANSACTION_STATE_LOG_REPLICATION_FACTOR=1",
+			"KAFKA_TRANSACTION_STATE_LOG_MIN_ISR=1",
+			"KAFKA_GROUP_INITIAL_REBALANCE_DELAY_MS=0",
+		},
+		Wait:             WaitForKafkaMetadata(),
+		ConnectionString: func(c *Container) string { return c.Addr() },
+	})
+}
+
+// KafkaContainer is a container running a single Kafka broker in KRaft mode, without ZooKeeper.
+type KafkaContainer struct {
+	*Container
+
+	bootstrap string
+}
+
+// RunKafka runs a Kafka container with the default pool, see Pool.RunKafka.
+func RunKafka(opts ...RunOption) (*KafkaContainer, error) {
+	return defaultPool.RunKafka(opts...)
+}
+
+// RunKafka runs a Kafka broker. Kafka clients connect to the address the broker advertises rather
+// than the one they bootstrapped with, so the host port is chosen up front and advertised.
+// RunKafka returns once the broker answers metadata requests. As the advertised port differs
+// between runs, the broker cannot be reused or shared with WithReuse or WithShared.
+func (p *Pool) RunKafka(opts ...RunOption) (*KafkaContainer, error) {
+	o, err := newRunOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if o.ReuseKey != "" || o.SharedKey != "" {
+		return nil, fmt.Errorf("kafka does not support WithReuse or WithShared")
+	}
+	host, err := advertisedHost(p.log())
+	if err != nil {
+		return nil, err
+	}
+	port := randInt(1024, 49150)
+	bootstrap := net.JoinHostPort(host, strconv.Itoa(port))
+	opts = append([]RunOption{
+		WithHostPort(kafkaPort, port),
+		WithEnv("KAFKA_ADVERTISED_LISTENERS=PLAINTEXT://" + bootstrap + ",INTERNAL://" + kafkaInternal),
+	}, opts...)
+	c, err := p.RunService("kafka", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &KafkaContainer{Container: c, bootstrap: bootstrap}, nil
+}
+
+// advertisedHost returns the address published ports are reachable on from the host before
+// the container runs, resolved like lookup does. Where lookup uses the IP of each container,
+// the ports are published on all interfaces, so the loopback address is returned.
+func advertisedHost(l Logger) (string, error) {
+	detectDockerMachine(l)
+	host, err := dockerHost(l)
+	if err != nil {
+		return "", fmt.Errorf("error getting IP: %v", err)
+	}
+	if host == "" {
+		return "127.0.0.1", nil
+	}
+	return host, nil
+}
+
+// BootstrapServers returns the bootstrap servers clients connect to, such as "127.0.0.1:32768".
+func (c *KafkaContainer) BootstrapServers() string {
+	return c.bootstrap
+}
+
+// topics runs kafka-topics.sh inside the container against the broker.
+func (c *KafkaContainer) topics(args ...string) (string, error) {
+	out, err := c.Exec(append([]string{kafkaBin + "kafka-topics.sh", "--bootstrap-server", kafkaInternal}, args...)...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// CreateTopic creates the topic name with the given number of partitions. The broker is the
+// only one, so the replication factor is 1. Configs are topic configs like "retention.ms".
+func (c *KafkaContainer) CreateTopic(name string, partitions int, configs map[string]string) error {
+	args := []string{"--create", "--if-not-exists", "--topic", name, "--partitions", strconv.Itoa(partitions), "--replication-factor", "1"}
+	for k, v := range configs {
+		args = append(args, "--config", k+"="+v)
+	}
+	_, err := c.topics(args...)
+	return err
+}
+
+// DeleteTopic deletes the topic name.
+func (c *KafkaContainer) DeleteTopic(name string) error {
+	_, err := c.topics("--delete", "--if-exists", "--topic", name)
+	return err
+}
+
+// Topics returns the names of all topics.
+func (c *KafkaContainer) Topics() ([]string, error) {
+	out, err := c.topics("--list")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// WaitForKafkaMetadata waits until the broker behind the container's published port answers a
+// metadata request listing at least one broker. Kafka accepts TCP connections before it has
+// joined its cluster, so reachability alone is not enough.
+func WaitForKafkaMetadata() WaitStrategy {
+	return WaitFunc(func(c *Container, timeout time.Duration) error {
+		return retry(timeout, func() error {
+			brokers, err := kafkaMetadata(c.Addr())
+			if err != nil {
+				return err
+			}
+			if brokers == 0 {
+				return fmt.Errorf("no broker known yet")
+			}
+			return nil
+		})
+	})
+}
+
+// kafkaMetadata sends a version 0 metadata request for all topics to addr and returns the
+// number of brokers in the response.
+func kafkaMetadata(addr string) (int, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	const (
+		apiKeyMetadata = 3
+		correlationID  = 42
+		clientID       = "dockertest"
+	)
+	req := make([]byte, 0, 64)
+	req = binary.BigEndian.AppendUint16(req, apiKeyMetadata)
+	req = binary.BigEndian.AppendUint16(req, 0)
+	req = binary.BigEndian.AppendUint32(req, correlationID)
+	req = binary.BigEndian.AppendUint16(req, uint16(len(clientID)))
+	req = append(req, clientID...)
+	req = binary.BigEndian.AppendUint32(req, 0) // empty topic list: all topics
+	if _, err := conn.Write(binary.BigEndian.AppendUint32(nil, uint32(len(req)))); err != nil {
+		return 0, err
+	}
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+
+	r := bufio.NewReader(conn)
+	var header struct {
+		Size, CorrelationID int32
+		Brokers             int32
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		if err == io.EOF {
+			return 0, fmt.Errorf("broker closed the connection")
+		}
+		return 0, err
+	}
+	if header.CorrelationID != correlationID {
+		return 0, fmt.Errorf("unexpected correlation id %d", header.CorrelationID)
+	}
+	return int(header.Brokers), nil
+}
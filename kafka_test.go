@@ -0,0 +1,80 @@
+package dockertest
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestKafkaMetadata(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		var size int32
+		binary.Read(c, binary.BigEndian, &size)
+		req := make([]byte, size)
+		io.ReadFull(c, req)
+		if binary.BigEndian.Uint16(req) != 3 {
+			return
+		}
+		// Response: size, the request's correlation id and a list of two brokers.
+		resp := []int32{12, int32(binary.BigEndian.Uint32(req[4:])), 2}
+		binary.Write(c, binary.BigEndian, resp)
+	}()
+
+	brokers, err := kafkaMetadata(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if brokers != 2 {
+		t.Errorf("expected 2 brokers, got %d", brokers)
+	}
+}
+
+func TestRunKafkaRejectsReuse(t *testing.T) {
+	p := &Pool{Logger: NopLogger}
+	for name, opt := range map[string]RunOption{"reuse": WithReuse("kafka"), "shared": WithShared("kafka")} {
+		if _, err := p.RunKafka(opt); err == nil || !strings.Contains(err.Error(), "WithReuse") {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+}
+
+func TestAdvertisedHost(t *testing.T) {
+	// Detect docker-machine before faking it, so that the detection does not overwrite the settings below.
+	detectDockerMachine(NopLogger)
+	fakeCommand(t, "docker-machine", `case "$1" in ip) echo 192.168.99.100 ;; esac`)
+	defer func(a bool, b string) { DockerMachineAvailable, BindDockerToLocalhost = a, b }(DockerMachineAvailable, BindDockerToLocalhost)
+
+	for _, tc := range []struct {
+		machine bool
+		bind    string
+		host    string
+	}{
+		{true, "", "192.168.99.100"},
+		{false, "1", "127.0.0.1"},
+		{false, "", "127.0.0.1"},
+	} {
+		DockerMachineAvailable, BindDockerToLocalhost = tc.machine, tc.bind
+		host, err := advertisedHost(NopLogger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if host != tc.host {
+			t.Errorf("machine %v, bind %q: got %s, want %s", tc.machine, tc.bind, host, tc.host)
+		}
+		if fixed, _ := dockerHost(NopLogger); fixed != "" && fixed != host {
+			t.Errorf("advertised host %s differs from lookup's %s", host, fixed)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package dockertest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// command runs "docker <verb> <args> <container>".
+func (c ContainerID) command(l Logger, verb string, args ...string) error {
+	args = append(append([]string{verb}, args...), string(c))
+	out, err := runDockerCommand(l, "docker", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Error running docker %s on %s: %v: %s", verb, c, err, out)
+	}
+	return nil
+}
+
+func stopArgs(timeout time.Duration) []string {
+	return []string{"-t", strconv.Itoa(int(timeout / time.Second))}
+}
+
+// Stop runs "docker stop" on the container, which kills it if it did not stop after timeout.
+func (c ContainerID) Stop(timeout time.Duration) error {
+	return c.command(Log, "stop", stopArgs(timeout)...)
+}
+
+// Start runs "docker start" on the container.
+func (c ContainerID) Start() error {
+	return c.command(Log, "start")
+}
+
+// Restart runs "docker restart" on the container.
+func (c ContainerID) Restart() error {
+	return c.command(Log, "restart")
+}
+
+// Pause runs "docker pause" on the container, which freezes all of its processes.
+func (c ContainerID) Pause() error {
+	return c.command(Log, "pause")
+}
+
+// Unpause runs "docker unpause" on the container.
+func (c ContainerID) Unpause() error {
+	return c.command(Log, "unpause")
+}
+
+// Stop stops the container, killing it if it did not stop after timeout.
+func (c *Container) Stop(timeout time.Duration) error {
+	return c.ContainerID.command(c.pool.log(), "stop", stopArgs(timeout)...)
+}
+
+// Start starts the stopped container and waits until it is ready again. The container keeps
+// its host port, so clients can reconnect to the same address.
+func (c *Container) Start() error {
+	if err := c.ContainerID.command(c.pool.log(), "start"); err != nil {
+		return err
+	}
+	return c.awaitRestart()
+}
+
+// Restart restarts the container and waits until it is ready again. The container keeps
+// its host port, so clients can reconnect to the same address.
+func (c *Container) Restart() error {
+	if err := c.ContainerID.command(c.pool.log(), "restart"); err != nil {
+		return err
+	}
+	return c.awaitRestart()
+}
+
+// Pause freezes all processes of the container, so that it accepts connections but never answers.
+func (c *Container) Pause() error {
+	return c.ContainerID.command(c.pool.log(), "pause")
+}
+
+// Unpause resumes the processes of a paused container.
+func (c *Container) Unpause() error {
+	return c.ContainerID.command(c.pool.log(), "unpause")
+}
+
+// awaitRestart waits until the published port of the restarted container is reachable and its
+// wait strategy passes. The container's IP may have changed, so Host is updated. The wait
+// strategy only sees the output since the restart.
+func (c *Container) awaitRestart() error {
+	l := c.pool.log()
+	// The daemon's clock is used, which may differ from ours on docker-machine.
+	out, err := runDockerCommand(l, "docker", "inspect", "-f", "{{.State.StartedAt}}", string(c.ContainerID)).Output()
+	if err != nil {
+		return fmt.Errorf("Error inspecting %s: %v", c.ContainerID, err)
+	}
+	c.startedAt = strings.TrimSpace(string(out))
+	host, err := c.ContainerID.lookup(l, c.Port, c.options.MaxWait)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.Host = host
+	c.mu.Unlock()
+	return c.waitUntilReady()
+}
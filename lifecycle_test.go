@@ -0,0 +1,52 @@
+package dockertest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRestartWaitsForNewLogs(t *testing.T) {
+	port := hostPort(echoListener(t, "127.0.0.1:0"))
+	bind := BindDockerToLocalhost
+	BindDockerToLocalhost = "1"
+	defer func() { BindDockerToLocalhost = bind }()
+
+	// The container logged the text before the restart. After the restart, it is logged once
+	// the file logged exists.
+	dir := t.TempDir()
+	logged, calls := filepath.Join(dir, "logged"), filepath.Join(dir, "calls")
+	fakeDocker(t, fmt.Sprintf(`case "$1" in
+inspect) echo 2026-01-02T03:04:05.123456789Z ;;
+logs)
+	echo "$@" >> %s
+	if [ "$2" != --since ] || [ -e %s ]; then echo "worker is now running"; fi ;;
+esac
+`, calls, logged))
+
+	c := &Container{ContainerID: "abc123", Host: "127.0.0.1", Port: port, pool: &Pool{Logger: NopLogger},
+		options: &RunOptions{Wait: WaitForLog("worker is now running", 1), MaxWait: 500 * time.Millisecond}}
+	if err := c.waitUntilReady(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Restart(); err == nil {
+		t.Fatal("restart did not wait for the text to be logged again")
+	}
+	if err := ioutil.WriteFile(logged, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if lines[0] != "logs abc123" || lines[len(lines)-1] != "logs --since 2026-01-02T03:04:05.123456789Z abc123" {
+		t.Errorf("unexpected logs calls %q", lines)
+	}
+}
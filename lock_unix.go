@@ -0,0 +1,34 @@
+//go:build !windows
+// +build !windows
+
+package dockertest
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile opens path and blocks until it holds an exclusive lock on it.
+func lockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// unlockFile releases a lock acquired with lockFile.
+func unlockFile(f *os.File) error {
+	defer f.Close()
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+// processAlive returns true if a process with the given pid exists.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
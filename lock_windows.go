@@ -0,0 +1,43 @@
+package dockertest
+
+import (
+	"os"
+	"time"
+)
+
+// staleLockAge is the age after which a lock file is considered left behind by a crashed process.
+const staleLockAge = 5 * time.Minute
+
+// lockFile creates path exclusively, waiting for other processes to remove it.
+func lockFile(path string) (*os.File, error) {
+	path += ".lck"
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			return f, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) > staleLockAge {
+			os.Remove(path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// unlockFile releases a lock acquired with lockFile.
+func unlockFile(f *os.File) error {
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// processAlive returns true if a process with the given pid exists.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,117 @@
+package dockertest
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+// Logger receives the messages dockertest emits while it manages containers.
+type Logger interface {
+	// Debugf logs verbose information such as every docker command that is executed.
+	Debugf(format string, v ...interface{})
+	// Infof logs progress information such as the container being set up.
+	Infof(format string, v ...interface{})
+	// Warnf logs problems that do not stop dockertest from working.
+	Warnf(format string, v ...interface{})
+}
+
+// Level is the severity of a log message.
+type Level int
+
+const (
+	// LevelDebug is the level of verbose messages like executed docker commands.
+	LevelDebug Level = iota
+	// LevelInfo is the level of progress messages.
+	LevelInfo
+	// LevelWarn is the level of warnings.
+	LevelWarn
+)
+
+// String returns the upper case name of the level.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// Log is the logger used by the package level functions and by pools that have no logger of their own.
+// It writes info and warning messages to stderr. Set it to NopLogger to silence dockertest.
+var Log = WriterLogger(os.Stderr, LevelInfo)
+
+// NopLogger discards all messages.
+var NopLogger Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+
+// WriterLogger returns a logger writing messages of level min and above to w.
+func WriterLogger(w io.Writer, min Level) Logger {
+	return &writerLogger{l: log.New(w, "dockertest: ", log.LstdFlags), min: min}
+}
+
+type writerLogger struct {
+	l   *log.Logger
+	min Level
+}
+
+func (w *writerLogger) logf(level Level, format string, v ...interface{}) {
+	if level < w.min {
+		return
+	}
+	w.l.Printf("%s %s", level, fmt.Sprintf(format, v...))
+}
+
+func (w *writerLogger) Debugf(format string, v ...interface{}) { w.logf(LevelDebug, format, v...) }
+func (w *writerLogger) Infof(format string, v ...interface{})  { w.logf(LevelInfo, format, v...) }
+func (w *writerLogger) Warnf(format string, v ...interface{})  { w.logf(LevelWarn, format, v...) }
+
+// TestLogger returns a logger writing all messages to tb.Logf, so that they are only shown
+// for failing tests or when running go test -v.
+func TestLogger(tb testing.TB) Logger {
+	return testLogger{tb}
+}
+
+type testLogger struct {
+	tb testing.TB
+}
+
+func (t testLogger) Debugf(format string, v ...interface{}) {
+	t.tb.Helper()
+	t.tb.Logf("%s %s", LevelDebug, fmt.Sprintf(format, v...))
+}
+
+func (t testLogger) Infof(format string, v ...interface{}) {
+	t.tb.Helper()
+	t.tb.Logf("%s %s", LevelInfo, fmt.Sprintf(format, v...))
+}
+
+func (t testLogger) Warnf(format string, v ...interface{}) {
+	t.tb.Helper()
+	t.tb.Logf("%s %s", LevelWarn, fmt.Sprintf(format, v...))
+}
+
+// SlogLogger returns a logger forwarding messages to l at the matching slog level.
+func SlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Debugf(format string, v ...interface{}) { s.l.Debug(fmt.Sprintf(format, v...)) }
+func (s slogLogger) Infof(format string, v ...interface{})  { s.l.Info(fmt.Sprintf(format, v...)) }
+func (s slogLogger) Warnf(format string, v ...interface{})  { s.l.Warn(fmt.Sprintf(format, v...)) }
@@ -0,0 +1,55 @@
+package dockertest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriterLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := WriterLogger(&buf, LevelInfo)
+	l.Debugf("hidden %d", 1)
+	l.Infof("shown %d", 2)
+	l.Warnf("shown %d", 3)
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Errorf("debug message logged at info level: %q", out)
+	}
+	if !strings.Contains(out, "INFO shown 2") || !strings.Contains(out, "WARN shown 3") {
+		t.Errorf("missing messages: %q", out)
+	}
+}
+
+func TestDockerCommandIsLogged(t *testing.T) {
+	var buf bytes.Buffer
+	if err := runDockerCommand(WriterLogger(&buf, LevelDebug), "true", "arg").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "DEBUG true arg (") {
+		t.Errorf("command not logged: %q", out)
+	}
+}
+
+func TestDockerMachineCommandsAreLogged(t *testing.T) {
+	fakeCommand(t, "docker-machine", `case "$1" in ip) echo 127.0.0.1 ;; esac`)
+	defer func(a bool) { DockerMachineAvailable = a }(DockerMachineAvailable)
+	DockerMachineAvailable = true
+	port := hostPort(echoListener(t, "127.0.0.1:0"))
+
+	var buf bytes.Buffer
+	l := WriterLogger(&buf, LevelDebug)
+	if !startDockerMachine(l) {
+		t.Error("docker-machine start failed")
+	}
+	if _, err := ContainerID("abc123").lookup(l, port, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, cmd := range []string{"DEBUG docker-machine start " + DockerMachineName + " (", "DEBUG docker-machine ip " + DockerMachineName + " ("} {
+		if !strings.Contains(out, cmd) {
+			t.Errorf("%q not logged: %q", cmd, out)
+		}
+	}
+}
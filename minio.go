@@ -0,0 +1,237 @@
+package dockertest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	minioPort = 9000
+	// minioRegion is the region MinIO uses unless configured otherwise. Requests are signed for it.
+	minioRegion = "us-east-1"
+)
+
+func init() {
+	MustRegister(&Service{
+		Name:             "minio",
+		Image:            minioImage,
+		Ports:            []int{minioPort},
+		Env:              []string{"MINIO_ROOT_USER=" + MinIOAccessKey, "MINIO_ROOT_PASSWORD=" + MinIOSecretKey},
+		Cmd:              []string{"server", "/data"},
+		Wait:             WaitForHTTP("/minio/health/live", http.StatusOK),
+		Username:         MinIOAccessKey,
+		Password:         MinIOSecretKey,
+		ConnectionString: func(c *Container) string { return "http://" + c.Addr() },
+	})
+}
+
+// MinIOContainer is a container running MinIO, an S3 compatible object storage.
+type MinIOContainer struct {
+	*Container
+}
+
+// RunMinIO runs a MinIO container with the default pool, see Pool.RunMinIO.
+func RunMinIO(opts ...RunOption) (*MinIOContainer, error) {
+	return defaultPool.RunMinIO(opts...)
+}
+
+// RunMinIO runs a MinIO server and returns once its health endpoint reports it live. The
+// credentials are MinIOAccessKey and MinIOSecretKey unless set with WithMinIOCredentials.
+// The server starts empty; load fixtures with LoadFixtures or UploadDir.
+func (p *Pool) RunMinIO(opts ...RunOption) (*MinIOContainer, error) {
+	c, err := p.RunService("minio", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &MinIOContainer{c}, nil
+}
+
+// WithMinIOCredentials sets the access key and secret key of the MinIO root user.
+func WithMinIOCredentials(accessKey, secretKey string) RunOption {
+	return func(o *RunOptions) error {
+		// MinIO refuses to start with shorter keys.
+		if len(accessKey) < 3 || len(secretKey) < 8 {
+			return fmt.Errorf("minio access key must have at least 3 and secret key at least 8 characters")
+		}
+		o.Env = append(o.Env, "MINIO_ROOT_USER="+accessKey, "MINIO_ROOT_PASSWORD="+secretKey)
+		return nil
+	}
+}
+
+// Endpoint returns the URL S3 clients connect to, such as "http://127.0.0.1:32768". Clients
+// must use path style requests.
+func (c *MinIOContainer) Endpoint() string {
+	return "http://" + c.Addr()
+}
+
+// AccessKey returns the access key of the root user.
+func (c *MinIOContainer) AccessKey() string {
+	return envValue(c.options.Env, "MINIO_ROOT_USER")
+}
+
+// SecretKey returns the secret key of the root user.
+func (c *MinIOContainer) SecretKey() string {
+	return envValue(c.options.Env, "MINIO_ROOT_PASSWORD")
+}
+
+// Region returns the region requests must be signed for.
+func (c *MinIOContainer) Region() string {
+	return minioRegion
+}
+
+// CreateBucket creates the bucket name.
+func (c *MinIOContainer) CreateBucket(name string) error {
+	return c.do("PUT", "/"+name, nil)
+}
+
+// PutObject stores data as the object key of bucket.
+func (c *MinIOContainer) PutObject(bucket, key string, data []byte) error {
+	return c.do("PUT", "/"+bucket+"/"+key, data)
+}
+
+// UploadDir stores every file below dir in bucket, using the slash separated path relative to
+// dir as object key.
+func (c *MinIOContainer) UploadDir(bucket, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return c.PutObject(bucket, filepath.ToSlash(rel), data)
+	})
+}
+
+// LoadFixtures creates a bucket for every directory in dir and uploads its contents with
+// UploadDir. Files directly in dir are ignored.
+func (c *MinIOContainer) LoadFixtures(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if err := c.CreateBucket(e.Name()); err != nil {
+			return err
+		}
+		if err := c.UploadDir(e.Name(), filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// do sends a request signed with the root credentials and fails unless it succeeds.
+func (c *MinIOContainer) do(method, path string, body []byte) error {
+	req, err := http.NewRequest(method, c.Endpoint(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.URL.Path = path
+	req.URL.RawPath = s3Escape(path, true)
+	signV4(req, body, c.AccessKey(), c.SecretKey(), minioRegion, time.Now())
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Error requesting %s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// signV4 signs req for S3 with AWS Signature Version 4, see
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html.
+func signV4(req *http.Request, body []byte, accessKey, secretKey, region string, now time.Time) {
+	now = now.UTC()
+	date := now.Format("20060102")
+	payload := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payload[:]))
+
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	headers := ""
+	for _, h := range signed {
+		v := req.Header.Get(h)
+		if h == "host" {
+			v = req.URL.Host
+		}
+		headers += h + ":" + strings.TrimSpace(v) + "\n"
+	}
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3Query(req),
+		headers,
+		strings.Join(signed, ";"),
+		hex.EncodeToString(payload[:]),
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonical))
+	scope := date + "/" + region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + secretKey)
+	for _, s := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, strings.Join(signed, ";"), hex.EncodeToString(hmacSHA256(key, toSign))))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Query returns the canonical query string of req, with parameters sorted by name.
+func s3Query(req *http.Request) string {
+	values := req.URL.Query()
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, k := range keys {
+		for _, v := range values[k] {
+			params = append(params, s3Escape(k, false)+"="+s3Escape(v, false))
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// s3Escape percent-encodes all bytes of s except unreserved characters, as required for
+// canonical requests. Slashes are kept in paths.
+func s3Escape(s string, path bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch >= 'A' && ch <= 'Z' || ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' || strings.IndexByte("-._~", ch) >= 0 || path && ch == '/' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,139 @@
+package dockertest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MongoContainer is a container running MongoDB.
+type MongoContainer struct {
+	*Container
+}
+
+// RunMongo runs a standalone MongoDB server with the default pool, see Pool.RunMongo.
+func RunMongo(opts ...RunOption) (*MongoContainer, error) {
+	return defaultPool.RunMongo(opts...)
+}
+
+// RunMongo runs a standalone MongoDB server.
+func (p *Pool) RunMongo(opts ...RunOption) (*MongoContainer, error) {
+	c, err := p.RunService("mongo", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &MongoContainer{Container: c}, nil
+}
+
+// RunMongoReplicaSet runs a single node replica set with the default pool, see Pool.RunMongoReplicaSet.
+func RunMongoReplicaSet(name string, opts ...RunOption) (*MongoContainer, error) {
+	return defaultPool.RunMongoReplicaSet(name, opts...)
+}
+
+// RunMongoReplicaSet runs a single node replica set called name, which is needed for
+// transactions and change streams. It returns once the node has been elected primary.
+//
+// Drivers connect to the member addresses of the replica set configuration, and a member must
+// recognize its own address. mongod therefore listens inside the container on the port it is
+// published on, and the member is configured with the address reachable from the host. As the
+// port differs between runs, the replica set cannot be reused or shared with WithReuse or
+// WithShared.
+func (p *Pool) RunMongoReplicaSet(name string, opts ...RunOption) (*MongoContainer, error) {
+	if name == "" {
+		return nil, fmt.Errorf("replica set name must not be empty")
+	}
+	o, err := newRunOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if o.ReuseKey != "" || o.SharedKey != "" {
+		return nil, fmt.Errorf("mongo replica sets do not support WithReuse or WithShared")
+	}
+	s, ok := Lookup("mongo")
+	if !ok {
+		return nil, fmt.Errorf("unknown service mongo")
+	}
+	host, err := advertisedHost(p.log())
+	if err != nil {
+		return nil, err
+	}
+	port := randInt(1024, 49150)
+	opts = append([]RunOption{
+		WithHostPort(port, port),
+		WithCmd("--replSet", name, "--bind_ip_all", "--port", strconv.Itoa(port)),
+	}, opts...)
+	c, err := p.runService(s, port, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m := &MongoContainer{Container: c}
+
+	member := fmt.Sprintf("%s:%d", host, port)
+	if _, err := m.Eval(fmt.Sprintf(`rs.initiate({_id: %q, members: [{_id: 0, host: %q}]})`, name, member)); err != nil {
+		c.ForceKillRemove()
+		return nil, err
+	}
+	err = retry(c.options.MaxWait, func() error {
+		out, err := m.Eval("db.isMaster().ismaster")
+		if err != nil {
+			return err
+		}
+		if out != "true" {
+			return fmt.Errorf("no primary elected")
+		}
+		return nil
+	})
+	if err != nil {
+		c.ForceKillRemove()
+		return nil, fmt.Errorf("replica set %s is not ready: %v", name, err)
+	}
+	return m, nil
+}
+
+// ReplicaSet returns the name of the replica set, or "" if the server runs standalone.
+func (m *MongoContainer) ReplicaSet() string {
+	return mongoReplicaSet(m.options.Cmd)
+}
+
+// URI returns the connection string, such as "mongodb://127.0.0.1:32768/?replicaSet=rs0".
+func (m *MongoContainer) URI() string {
+	return mongoURI(m.Container)
+}
+
+// Eval evaluates the JavaScript js with the mongo shell inside the container and returns its
+// output. It uses mongosh, or the legacy mongo shell of images before MongoDB 6.
+func (m *MongoContainer) Eval(js string) (string, error) {
+	// mongod listens on the main port of the container, which differs for replica sets.
+	port := strconv.Itoa(m.ContainerPort)
+	var out []byte
+	var err error
+	for _, shell := range []string{"mongosh", "mongo"} {
+		out, err = m.Exec(shell, "--quiet", "--port", port, "--eval", js)
+		if err == nil || !strings.Contains(err.Error(), "executable file not found") {
+			break
+		}
+	}
+	return strings.TrimSpace(string(out)), err
+}
+
+// mongoURI returns the connection string of c. Drivers need the replicaSet parameter to
+// discover the primary of a replica set.
+func mongoURI(c *Container) string {
+	if set := mongoReplicaSet(c.options.Cmd); set != "" {
+		return fmt.Sprintf("mongodb://%s/?replicaSet=%s", c.Addr(), set)
+	}
+	return "mongodb://" + c.Addr()
+}
+
+// mongoReplicaSet returns the value of the --replSet argument of mongod, if any.
+func mongoReplicaSet(cmd []string) string {
+	for i, arg := range cmd {
+		if arg == "--replSet" && i+1 < len(cmd) {
+			return cmd[i+1]
+		}
+		if strings.HasPrefix(arg, "--replSet=") {
+			return strings.TrimPrefix(arg, "--replSet=")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,31 @@
+package dockertest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMongoURI(t *testing.T) {
+	for _, tc := range []struct {
+		cmd      []string
+		expected string
+	}{
+		{nil, "mongodb://127.0.0.1:27017"},
+		{[]string{"--replSet", "rs0", "--bind_ip_all"}, "mongodb://127.0.0.1:27017/?replicaSet=rs0"},
+		{[]string{"--replSet=rs1"}, "mongodb://127.0.0.1:27017/?replicaSet=rs1"},
+	} {
+		c := &Container{Host: "127.0.0.1", Port: 27017, options: &RunOptions{Cmd: tc.cmd}}
+		if uri := mongoURI(c); uri != tc.expected {
+			t.Errorf("%v: expected %s, got %s", tc.cmd, tc.expected, uri)
+		}
+	}
+}
+
+func TestRunMongoReplicaSetRejectsReuse(t *testing.T) {
+	p := &Pool{Logger: NopLogger}
+	for name, opt := range map[string]RunOption{"reuse": WithReuse("mongo"), "shared": WithShared("mongo")} {
+		if _, err := p.RunMongoReplicaSet("rs0", opt); err == nil || !strings.Contains(err.Error(), "WithReuse") {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+}
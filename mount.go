@@ -0,0 +1,182 @@
+package dockertest
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pborman/uuid"
+)
+
+// volumeLabel is set on the volumes created by dockertest.
+const volumeLabel = "io.dockertest.volume"
+
+// DockerMachineSharedFolders are the host directories docker-machine shares with its VM by
+// default. Bind mounts of other directories are refused when docker-machine is used, because
+// the container would see an empty directory of the VM instead.
+var DockerMachineSharedFolders = []string{"/Users", "/c/Users", `C:\Users`}
+
+// Mount is a bind mount, named volume or tmpfs mounted into a container.
+type Mount struct {
+	// Type is "bind", "volume" or "tmpfs".
+	Type string
+	// Source is the host path of a bind mount or the name of a volume.
+	Source string
+	// Target is the path in the container.
+	Target string
+	// ReadOnly mounts a bind mount or volume read-only.
+	ReadOnly bool
+	// TmpfsSize limits the size of a tmpfs in bytes. Zero means unlimited.
+	TmpfsSize int64
+}
+
+// arg returns the value of the --mount argument of "docker run".
+func (m Mount) arg() string {
+	fields := []string{"type=" + m.Type}
+	if m.Source != "" {
+		fields = append(fields, "source="+m.Source)
+	}
+	fields = append(fields, "target="+m.Target)
+	if m.ReadOnly {
+		fields = append(fields, "readonly")
+	}
+	if m.TmpfsSize > 0 {
+		fields = append(fields, fmt.Sprintf("tmpfs-size=%d", m.TmpfsSize))
+	}
+	return strings.Join(fields, ",")
+}
+
+func withMount(m Mount) RunOption {
+	return func(o *RunOptions) error {
+		if !path.IsAbs(m.Target) {
+			return fmt.Errorf("mount target %q is not an absolute path", m.Target)
+		}
+		if strings.Contains(m.Source, ",") || strings.Contains(m.Target, ",") {
+			return fmt.Errorf("mount %s:%s must not contain commas", m.Source, m.Target)
+		}
+		for _, other := range o.Mounts {
+			if other.Target == m.Target {
+				return fmt.Errorf("%s is mounted twice", m.Target)
+			}
+		}
+		o.Mounts = append(o.Mounts, m)
+		return nil
+	}
+}
+
+// WithBind mounts the host directory or file hostPath at target. Relative paths are resolved
+// against the working directory.
+func WithBind(hostPath, target string, readOnly bool) RunOption {
+	return func(o *RunOptions) error {
+		abs, err := filepath.Abs(hostPath)
+		if err != nil {
+			return err
+		}
+		return withMount(Mount{Type: "bind", Source: abs, Target: target, ReadOnly: readOnly})(o)
+	}
+}
+
+// WithVolume mounts the named volume at target. Docker creates the volume if it does not exist;
+// use Pool.CreateVolume to have it removed by Purge.
+func WithVolume(name, target string) RunOption {
+	return func(o *RunOptions) error {
+		if name == "" {
+			return fmt.Errorf("volume name must not be empty")
+		}
+		return withMount(Mount{Type: "volume", Source: name, Target: target})(o)
+	}
+}
+
+// WithTmpfs mounts a tmpfs of at most size bytes at target, or of unlimited size if size is zero.
+// Putting the data directory of a database on a tmpfs speeds up tests considerably.
+func WithTmpfs(target string, size int64) RunOption {
+	return func(o *RunOptions) error {
+		if size < 0 {
+			return fmt.Errorf("tmpfs size must not be negative, got %d", size)
+		}
+		return withMount(Mount{Type: "tmpfs", Target: target, TmpfsSize: size})(o)
+	}
+}
+
+// checkSharedFolders returns an error if a bind mount cannot work with docker-machine.
+func (o *RunOptions) checkSharedFolders() error {
+	if !DockerMachineAvailable {
+		return nil
+	}
+	for _, m := range o.Mounts {
+		if m.Type != "bind" || inSharedFolder(m.Source) {
+			continue
+		}
+		return fmt.Errorf("bind mount %s is outside of the folders docker-machine shares with its VM (%s)", m.Source, strings.Join(DockerMachineSharedFolders, ", "))
+	}
+	return nil
+}
+
+func inSharedFolder(p string) bool {
+	for _, dir := range DockerMachineSharedFolders {
+		dir = strings.TrimRight(dir, `/\`)
+		if p == dir || strings.HasPrefix(p, dir+"/") || strings.HasPrefix(p, dir+`\`) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateVolume creates a named volume, which is removed by Purge unless a container kept for
+// reuse still uses it. If name is empty, a unique name is generated. It returns the name of
+// the volume.
+func (p *Pool) CreateVolume(name string) (string, error) {
+	if name == "" {
+		name = "dockertest-" + uuid.New()
+	}
+	out, err := runDockerCommand(p.log(), "docker", "volume", "create", "--label", volumeLabel+"=true", name).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("Error creating volume %s: %v: %s", name, err, out)
+	}
+	p.mu.Lock()
+	p.volumes = append(p.volumes, name)
+	p.mu.Unlock()
+	return name, nil
+}
+
+// RemoveVolume removes the named volume. No container may use it anymore.
+func (p *Pool) RemoveVolume(name string) error {
+	p.mu.Lock()
+	for i, v := range p.volumes {
+		if v == name {
+			p.volumes = append(p.volumes[:i], p.volumes[i+1:]...)
+			break
+		}
+	}
+	p.mu.Unlock()
+	return removeVolume(p.log(), name)
+}
+
+func removeVolume(l Logger, name string) error {
+	if Debug {
+		return nil
+	}
+	out, err := runDockerCommand(l, "docker", "volume", "rm", name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Error removing volume %s: %v: %s", name, err, out)
+	}
+	return nil
+}
+
+// removeUnusedVolume removes the named volume unless a container still uses it. Docker refuses
+// to remove volumes of existing containers, such as the ones KillRemove keeps.
+func removeUnusedVolume(l Logger, name string) error {
+	if Debug {
+		return nil
+	}
+	out, err := runDockerCommand(l, "docker", "ps", "-a", "-q", "--filter", "volume="+name).Output()
+	if err != nil {
+		return fmt.Errorf("Error listing containers using volume %s: %v", name, err)
+	}
+	if ids := strings.Fields(string(out)); len(ids) > 0 {
+		l.Infof("keeping volume %s used by %s", name, strings.Join(ids, ", "))
+		return nil
+	}
+	return removeVolume(l, name)
+}
@@ -0,0 +1,83 @@
+package dockertest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMountArgs(t *testing.T) {
+	o, err := newRunOptions([]RunOption{
+		WithBind("/Users/me/fixtures", "/fixtures", true),
+		WithVolume("data", "/data"),
+		WithTmpfs("/var/lib/postgresql/data", 256<<20),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(o.runArgs("n", "postgres", map[int]int{2: 1}), " ")
+	for _, want := range []string{
+		"--mount type=bind,source=/Users/me/fixtures,target=/fixtures,readonly",
+		"--mount type=volume,source=data,target=/data",
+		"--mount type=tmpfs,target=/var/lib/postgresql/data,tmpfs-size=268435456",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%q not in %q", want, got)
+		}
+	}
+
+	defer func(a bool) { DockerMachineAvailable = a }(DockerMachineAvailable)
+	DockerMachineAvailable = true
+	if err := o.checkSharedFolders(); err != nil {
+		t.Errorf("bind mount in /Users refused: %v", err)
+	}
+	o, err = newRunOptions([]RunOption{WithBind("/opt/fixtures", "/fixtures", false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.checkSharedFolders(); err == nil {
+		t.Error("bind mount outside of shared folders accepted")
+	}
+}
+
+func TestInvalidMounts(t *testing.T) {
+	for name, opts := range map[string][]RunOption{
+		"relative target": {WithTmpfs("data", 0)},
+		"negative size":   {WithTmpfs("/data", -1)},
+		"twice":           {WithVolume("a", "/data"), WithTmpfs("/data", 0)},
+		"empty volume":    {WithVolume("", "/data")},
+	} {
+		if _, err := newRunOptions(opts); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestPurgeKeepsVolumesInUse(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	fakeDocker(t, fmt.Sprintf(`echo "$@" >> %s
+case "$1 $4 $5" in
+"ps --filter volume=kept") echo abc123 ;;
+esac
+`, calls))
+
+	p := &Pool{Logger: NopLogger}
+	p.volumes = []string{"kept", "unused"}
+	if err := p.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"ps -a -q --filter volume=kept",
+		"ps -a -q --filter volume=unused",
+		"volume rm unused",
+	}
+	if got := strings.TrimSpace(string(out)); got != strings.Join(expected, "\n") {
+		t.Errorf("expected calls\n%s\ngot\n%s", strings.Join(expected, "\n"), got)
+	}
+}
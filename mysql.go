@@ -0,0 +1,198 @@
+package dockertest
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const mysqlPort = 3306
+
+func init() {
+	for _, s := range []struct{ name, image string }{{"mysql", mysqlImage}, {"mariadb", mariadbImage}} {
+		MustRegister(&Service{
+			Name:     s.name,
+			Image:    s.image,
+			Ports:    []int{mysqlPort},
+			Env:      []string{"MYSQL_ROOT_PASSWORD=" + MySQLPassword},
+			Wait:     WaitForMySQL(),
+			Username: MySQLUsername,
+			Password: MySQLPassword,
+			ConnectionString: func(c *Container) string {
+				return (&MySQLContainer{c}).DSN("")
+			},
+		})
+	}
+}
+
+// MySQLContainer is a container running MySQL or MariaDB.
+type MySQLContainer struct {
+	*Container
+}
+
+// RunMySQL runs a MySQL container with the default pool, see Pool.RunMySQL.
+func RunMySQL(opts ...RunOption) (*MySQLContainer, error) {
+	return defaultPool.RunMySQL(opts...)
+}
+
+// RunMySQL runs a MySQL container. The password of MySQLUsername is MySQLPassword. It returns
+// once the server accepts connections after running the init scripts, see WaitForMySQL.
+func (p *Pool) RunMySQL(opts ...RunOption) (*MySQLContainer, error) {
+	c, err := p.RunService("mysql", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &MySQLContainer{c}, nil
+}
+
+// RunMariaDB runs a MariaDB container with the default pool, see Pool.RunMariaDB.
+func RunMariaDB(opts ...RunOption) (*MySQLContainer, error) {
+	return defaultPool.RunMariaDB(opts...)
+}
+
+// RunMariaDB runs a MariaDB container. It takes the same options as RunMySQL.
+func (p *Pool) RunMariaDB(opts ...RunOption) (*MySQLContainer, error) {
+	c, err := p.RunService("mariadb", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &MySQLContainer{c}, nil
+}
+
+// WithMySQLDatabase creates the database name on the first start.
+func WithMySQLDatabase(name string) RunOption {
+	return func(o *RunOptions) error {
+		if name == "" {
+			return fmt.Errorf("mysql database name must not be empty")
+		}
+		o.Env = append(o.Env, "MYSQL_DATABASE="+name)
+		return nil
+	}
+}
+
+// WithMySQLUser creates user with password on the first start. The user is granted all
+// privileges on the database of WithMySQLDatabase.
+func WithMySQLUser(user, password string) RunOption {
+	return func(o *RunOptions) error {
+		if user == "" || user == MySQLUsername {
+			return fmt.Errorf("mysql user must not be empty or %s", MySQLUsername)
+		}
+		o.Env = append(o.Env, "MYSQL_USER="+user, "MYSQL_PASSWORD="+password)
+		return nil
+	}
+}
+
+// WithMySQLCharset sets the default character set and collation of the server, such as
+// "utf8mb4" and "utf8mb4_unicode_ci". If collation is empty, the default collation of the
+// character set is used.
+func WithMySQLCharset(charset, collation string) RunOption {
+	return func(o *RunOptions) error {
+		if charset == "" {
+			return fmt.Errorf("mysql character set must not be empty")
+		}
+		o.Cmd = append(o.Cmd, "--character-set-server="+charset)
+		if collation != "" {
+			o.Cmd = append(o.Cmd, "--collation-server="+collation)
+		}
+		return nil
+	}
+}
+
+// WithMySQLFlags passes server flags such as "--sql-mode=STRICT_ALL_TABLES" to mysqld.
+func WithMySQLFlags(flags ...string) RunOption {
+	return func(o *RunOptions) error {
+		for _, f := range flags {
+			if !strings.HasPrefix(f, "--") {
+				return fmt.Errorf("invalid mysql flag %q, flags start with --", f)
+			}
+		}
+		o.Cmd = append(o.Cmd, flags...)
+		return nil
+	}
+}
+
+// DSN returns the data source name of the given database in the format of github.com/go-sql-driver/mysql.
+func (c *MySQLContainer) DSN(database string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s", MySQLUsername, MySQLPassword, net.JoinHostPort(c.Host, strconv.Itoa(c.Port)), database)
+}
+
+// UserDSN returns the data source name of the user of WithMySQLUser for the database of
+// WithMySQLDatabase.
+func (c *MySQLContainer) UserDSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s", envValue(c.options.Env, "MYSQL_USER"), envValue(c.options.Env, "MYSQL_PASSWORD"),
+		net.JoinHostPort(c.Host, strconv.Itoa(c.Port)), envValue(c.options.Env, "MYSQL_DATABASE"))
+}
+
+// Query runs sql with the mysql client inside the container and returns the tab separated
+// output without column names. Recent MariaDB images only have the mariadb client, which is
+// used instead.
+func (c *MySQLContainer) Query(sql string) (string, error) {
+	script := mysqlShellClient + " -u " + shellQuote(MySQLUsername) + " -N -B -e " + shellQuote(sql)
+	out, err := c.ContainerID.exec(c.pool.log(), []string{"MYSQL_PWD=" + MySQLPassword}, "sh", "-c", script)
+	return strings.TrimSpace(string(out)), err
+}
+
+// CreateDatabase creates the database name.
+func (c *MySQLContainer) CreateDatabase(name string) error {
+	_, err := c.Query(fmt.Sprintf("CREATE DATABASE `%s`", name))
+	return err
+}
+
+// DropDatabase drops the database name.
+func (c *MySQLContainer) DropDatabase(name string) error {
+	_, err := c.Query(fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", name))
+	return err
+}
+
+// NewDatabase creates a uniquely named database for the test and drops it when the test finishes.
+// It returns the DSN of the new database.
+func (c *MySQLContainer) NewDatabase(tb testing.TB) string {
+	tb.Helper()
+	name := uniqueDatabaseName(tb)
+	if err := c.CreateDatabase(name); err != nil {
+		tb.Fatalf("Could not create database: %v", err)
+	}
+	tb.Cleanup(func() {
+		if err := c.DropDatabase(name); err != nil {
+			tb.Errorf("Could not drop database %s: %v", name, err)
+		}
+	})
+	return c.DSN(name)
+}
+
+// mysqlReady matches the message mysqld logs when it accepts connections. While the init
+// scripts run, the entrypoint starts a temporary server logging port 0.
+var mysqlReady = regexp.MustCompile(`ready for connections\.?\s+Version: '[^']*'\s+socket: '[^']*'\s+port: (\d+)`)
+
+// WaitForMySQL waits until MySQL or MariaDB logged that it accepts connections on its port and
+// answers a query. The port is reachable before the entrypoint has run the init scripts and
+// restarted the server, so reachability alone is not enough.
+func WaitForMySQL() WaitStrategy {
+	return WaitFunc(func(c *Container, timeout time.Duration) error {
+		return retry(timeout, func() error {
+			out, err := c.startLogs()
+			if err != nil {
+				return err
+			}
+			if !mysqlListening(string(out)) {
+				return fmt.Errorf("mysqld is not ready for connections")
+			}
+			_, err = (&MySQLContainer{c}).Query("SELECT 1")
+			return err
+		})
+	})
+}
+
+// mysqlListening returns true if logs contain a ready message of a server listening on a port.
+func mysqlListening(logs string) bool {
+	for _, m := range mysqlReady.FindAllStringSubmatch(logs, -1) {
+		if m[1] != "0" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package dockertest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestMySQLListening(t *testing.T) {
+	initializing := `2024-05-01T10:00:00.000000Z 0 [System] [MY-010931] [Server] /usr/sbin/mysqld: ready for connections. Version: '8.0.36'  socket: '/var/run/mysqld/mysqld.sock'  port: 0  MySQL Community Server - GPL.
+2024-05-01T10:00:00.000000Z 0 [System] [MY-011323] [Server] X Plugin ready for connections. Socket: /var/run/mysqld/mysqlx.sock
+`
+	ready := initializing + `2024-05-01T10:00:05.000000Z 0 [System] [MY-011323] [Server] X Plugin ready for connections. Bind-address: '::' port: 33060, socket: /var/run/mysqld/mysqlx.sock
+2024-05-01T10:00:05.000000Z 0 [System] [MY-010931] [Server] /usr/sbin/mysqld: ready for connections. Version: '8.0.36'  socket: '/var/run/mysqld/mysqld.sock'  port: 3306  MySQL Community Server - GPL.
+`
+	mariadb := `2024-05-01 10:00:05 0 [Note] mariadbd: ready for connections.
+Version: '11.3.2-MariaDB-1:11.3.2+maria~ubu2204'  socket: '/run/mysqld/mysqld.sock'  port: 3306  mariadb.org binary distribution
+`
+	for logs, expected := range map[string]bool{initializing: false, ready: true, mariadb: true, "": false} {
+		if mysqlListening(logs) != expected {
+			t.Errorf("expected %v for logs\n%s", expected, logs)
+		}
+	}
+}
+
+func TestWithInitScripts(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"01-schema.sql", "02-data.sql.gz", "03-users.sh", "README.md"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	o, err := newRunOptions([]RunOption{WithInitScripts(dir)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var targets []string
+	for _, m := range o.Mounts {
+		if m.Type != "bind" || !m.ReadOnly {
+			t.Errorf("unexpected mount %+v", m)
+		}
+		targets = append(targets, m.Target)
+	}
+	expected := []string{"/docker-entrypoint-initdb.d/01-schema.sql", "/docker-entrypoint-initdb.d/02-data.sql.gz", "/docker-entrypoint-initdb.d/03-users.sh"}
+	if len(targets) != len(expected) {
+		t.Fatalf("expected mounts %v, got %v", expected, targets)
+	}
+	for i := range expected {
+		if targets[i] != expected[i] {
+			t.Errorf("expected mounts %v, got %v", expected, targets)
+		}
+	}
+
+	if _, err := newRunOptions([]RunOption{WithInitScripts(filepath.Join(dir, "README.md"))}); err == nil {
+		t.Error("expected error for a file that is not an init script")
+	}
+	if _, err := newRunOptions([]RunOption{WithInitScripts(filepath.Join(dir, "missing.sql"))}); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func TestMySQLOptions(t *testing.T) {
+	o, err := newRunOptions([]RunOption{
+		WithMySQLDatabase("app"),
+		WithMySQLUser("app", "secret"),
+		WithMySQLCharset("utf8mb4", "utf8mb4_unicode_ci"),
+		WithMySQLFlags("--sql-mode=STRICT_ALL_TABLES"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &MySQLContainer{&Container{Host: "127.0.0.1", Port: 3306, options: o}}
+	if dsn := c.UserDSN(); dsn != "app:secret@tcp(127.0.0.1:3306)/app" {
+		t.Errorf("unexpected user DSN %s", dsn)
+	}
+	expected := []string{"--character-set-server=utf8mb4", "--collation-server=utf8mb4_unicode_ci", "--sql-mode=STRICT_ALL_TABLES"}
+	if len(o.Cmd) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, o.Cmd)
+	}
+	for i := range expected {
+		if o.Cmd[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, o.Cmd)
+		}
+	}
+	if _, err := newRunOptions([]RunOption{WithMySQLFlags("sql-mode=ANSI")}); err == nil {
+		t.Error("expected error for flag without --")
+	}
+	if _, err := newRunOptions([]RunOption{WithMySQLUser("root", "x")}); err == nil {
+		t.Error("expected error for root user")
+	}
+}
+
+func TestMySQLQueryMariaDBClient(t *testing.T) {
+	// The container only has the mariadb client, which prints its arguments.
+	bin := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(bin, "mariadb"), []byte("#!/bin/sh\necho \"$@\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// docker exec -e MYSQL_PWD=root abc123 sh -c script
+	fakeDocker(t, `shift 4; PATH=`+bin+` exec /bin/sh -c "$3"`)
+	c := &MySQLContainer{&Container{ContainerID: "abc123", pool: &Pool{Logger: NopLogger}}}
+	out, err := c.Query("SELECT 'a b'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "-u root -N -B -e SELECT 'a b'" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
@@ -0,0 +1,237 @@
+package dockertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	natsPort        = 4222
+	natsMonitorPort = 8222
+	natsClusterPort = 6222
+	// natsClusterSize is the number of servers of a cluster started by RunNATSCluster.
+	natsClusterSize = 3
+)
+
+func init() {
+	MustRegister(&Service{
+		Name:  "nats",
+		Image: natsImage,
+		Ports: []int{natsPort, natsMonitorPort},
+		// Arguments replace the configuration file of the image, which enables monitoring.
+		Cmd:              []string{"-m", strconv.Itoa(natsMonitorPort)},
+		Wait:             WaitForHTTPOnPort(natsMonitorPort, "/healthz", http.StatusOK),
+		ConnectionString: natsURL,
+	})
+}
+
+// NATSContainer is a container running a NATS server.
+type NATSContainer struct {
+	*Container
+}
+
+// RunNATS runs a NATS server with the default pool, see Pool.RunNATS.
+func RunNATS(opts ...RunOption) (*NATSContainer, error) {
+	return defaultPool.RunNATS(opts...)
+}
+
+// RunNATS runs a NATS server with monitoring enabled and returns once its health endpoint
+// reports it healthy. Enable JetStream with WithJetStream and authentication with
+// WithNATSToken or WithNATSUser.
+func (p *Pool) RunNATS(opts ...RunOption) (*NATSContainer, error) {
+	c, err := p.RunService("nats", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &NATSContainer{c}, nil
+}
+
+// WithJetStream enables JetStream, the persistence layer of NATS.
+func WithJetStream() RunOption {
+	return WithCmd("--jetstream")
+}
+
+// WithNATSToken requires clients to authenticate with token.
+func WithNATSToken(token string) RunOption {
+	return func(o *RunOptions) error {
+		if token == "" {
+			return fmt.Errorf("nats token must not be empty")
+		}
+		o.Cmd = append(o.Cmd, "--auth", token)
+		return nil
+	}
+}
+
+// WithNATSUser requires clients to authenticate as user with password.
+func WithNATSUser(user, password string) RunOption {
+	return func(o *RunOptions) error {
+		if user == "" {
+			return fmt.Errorf("nats user must not be empty")
+		}
+		o.Cmd = append(o.Cmd, "--user", user, "--pass", password)
+		return nil
+	}
+}
+
+// URL returns the URL clients connect with, such as "nats://127.0.0.1:32768", including the
+// token or user and password if authentication is enabled.
+func (c *NATSContainer) URL() string {
+	return natsURL(c.Container)
+}
+
+// MonitorURL returns the URL of the monitoring endpoints, such as "http://127.0.0.1:32769".
+func (c *NATSContainer) MonitorURL() string {
+	return "http://" + c.PortAddr(natsMonitorPort)
+}
+
+// natsURL returns the URL clients connect to c with, using the credentials given in its arguments.
+func natsURL(c *Container) string {
+	u := url.URL{Scheme: "nats", Host: c.Addr()}
+	args := natsArgs(c.options.Cmd)
+	if token, ok := args["--auth"]; ok {
+		u.User = url.User(token)
+	} else if user, ok := args["--user"]; ok {
+		u.User = url.UserPassword(user, args["--pass"])
+	}
+	return u.String()
+}
+
+// natsArgs returns the values of the flags in args. Later flags override earlier ones.
+func natsArgs(args []string) map[string]string {
+	values := map[string]string{}
+	for i := 0; i+1 < len(args); i++ {
+		if strings.HasPrefix(args[i], "-") {
+			values[args[i]] = args[i+1]
+		}
+	}
+	return values
+}
+
+// NATSCluster is a cluster of NATS servers attached to a network created for it, where they
+// route messages to each other.
+type NATSCluster struct {
+	// Servers are the servers of the cluster.
+	Servers []*NATSContainer
+
+	stack *Stack
+}
+
+// RunNATSCluster runs a NATS cluster with the default pool, see Pool.RunNATSCluster.
+func RunNATSCluster(opts ...RunOption) (*NATSCluster, error) {
+	return defaultPool.RunNATSCluster(opts...)
+}
+
+// RunNATSCluster runs a cluster of three NATS servers, each started with opts. The servers do
+// not advertise their addresses on the network to clients, which could not reach them; pass
+// URLs to clients instead. It returns once every server is healthy and routes to the others.
+func (p *Pool) RunNATSCluster(opts ...RunOption) (*NATSCluster, error) {
+	s, ok := Lookup("nats")
+	if !ok {
+		return nil, fmt.Errorf("unknown service nats")
+	}
+	// The stack runs the image directly, so the tag is resolved here.
+	o, err := newRunOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	tag := s.Tag
+	if o.Tag != "" {
+		tag = o.Tag
+	}
+	names := make([]string, natsClusterSize)
+	routes := make([]string, natsClusterSize)
+	for i := range names {
+		names[i] = fmt.Sprintf("nats-%d", i)
+		routes[i] = fmt.Sprintf("nats://%s:%d", names[i], natsClusterPort)
+	}
+	stack := NewStack(p)
+	for _, name := range names {
+		stack.Add(name, StackService{
+			Image: imageRef(s.Image, tag),
+			Port:  natsPort,
+			Options: append([]RunOption{
+				WithPorts(natsMonitorPort),
+				WithCmd(s.Cmd...),
+				WithCmd(
+					"--server_name", name,
+					"--cluster_name", "dockertest",
+					"--cluster", fmt.Sprintf("nats://0.0.0.0:%d", natsClusterPort),
+					"--routes", strings.Join(routes, ","),
+					"--no_advertise",
+				),
+				WithWait(s.Wait),
+			}, opts...),
+		})
+	}
+	// The servers start in parallel, as a JetStream cluster is not healthy before a majority runs.
+	if err := stack.Up(); err != nil {
+		return nil, err
+	}
+	cluster := &NATSCluster{stack: stack}
+	for _, name := range names {
+		cluster.Servers = append(cluster.Servers, &NATSContainer{stack.Container(name)})
+	}
+	var maxWait time.Duration
+	for _, server := range cluster.Servers {
+		if server.options.MaxWait > maxWait {
+			maxWait = server.options.MaxWait
+		}
+	}
+	err = retry(maxWait, func() error {
+		for i, server := range cluster.Servers {
+			routes, err := server.routes()
+			if err != nil {
+				return err
+			}
+			if routes < natsClusterSize-1 {
+				return fmt.Errorf("%s has %d of %d routes", names[i], routes, natsClusterSize-1)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		stack.Down()
+		return nil, fmt.Errorf("nats cluster is not ready: %v", err)
+	}
+	return cluster, nil
+}
+
+// routes returns the number of routes of the server to other servers of its cluster.
+func (c *NATSContainer) routes() (int, error) {
+	resp, err := (&http.Client{Timeout: 5 * time.Second}).Get(c.MonitorURL() + "/routez")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	var routez struct {
+		NumRoutes int `json:"num_routes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&routez); err != nil {
+		return 0, err
+	}
+	return routez.NumRoutes, nil
+}
+
+// URLs returns the URLs of all servers, for clients that accept several of them.
+func (c *NATSCluster) URLs() []string {
+	urls := make([]string, len(c.Servers))
+	for i, s := range c.Servers {
+		urls[i] = s.URL()
+	}
+	return urls
+}
+
+// Network returns the network the servers are attached to.
+func (c *NATSCluster) Network() *Network {
+	return c.stack.Network()
+}
+
+// KillRemove removes the servers and their network.
+func (c *NATSCluster) KillRemove() error {
+	return c.stack.Down()
+}
@@ -0,0 +1,143 @@
+package dockertest
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pborman/uuid"
+)
+
+// networkLabel is set on the networks created by dockertest.
+const networkLabel = "io.dockertest.network"
+
+// Network is a user defined docker network created by a pool. Containers attached to the same
+// network reach each other by name or alias.
+type Network struct {
+	// Name is the name of the network.
+	Name string
+	// ID is the id docker assigned to the network.
+	ID string
+
+	pool *Pool
+}
+
+// NetworkAttachment attaches a container to a network under the given aliases.
+type NetworkAttachment struct {
+	Network *Network
+	Aliases []string
+}
+
+// WithNetwork attaches the container to n. Other containers on n can reach it by the given aliases.
+func WithNetwork(n *Network, aliases ...string) RunOption {
+	return func(o *RunOptions) error {
+		if n == nil {
+			return fmt.Errorf("network must not be nil")
+		}
+		for _, a := range o.Networks {
+			if a.Network.Name == n.Name {
+				return fmt.Errorf("network %s given twice", n.Name)
+			}
+		}
+		o.Networks = append(o.Networks, NetworkAttachment{Network: n, Aliases: aliases})
+		return nil
+	}
+}
+
+// CreateNetwork creates a bridge network. If name is empty, a unique name is generated.
+// The network is removed by Purge or RemoveNetwork.
+func (p *Pool) CreateNetwork(name string) (*Network, error) {
+	if name == "" {
+		name = "dockertest-" + uuid.New()
+	}
+	out, err := runDockerCommand(p.log(), "docker", "network", "create", "--label", networkLabel+"=true", name).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("Error creating network %s: %v: %s", name, err, out)
+	}
+	n := &Network{Name: name, ID: strings.TrimSpace(string(out)), pool: p}
+	p.mu.Lock()
+	p.networks = append(p.networks, n)
+	p.mu.Unlock()
+	return n, nil
+}
+
+// RemoveNetwork removes the network n. Containers still attached to it, such as the ones kept
+// for reuse or shared with other processes, are disconnected first.
+func (p *Pool) RemoveNetwork(n *Network) error {
+	p.mu.Lock()
+	for i, o := range p.networks {
+		if o == n {
+			p.networks = append(p.networks[:i], p.networks[i+1:]...)
+			break
+		}
+	}
+	p.mu.Unlock()
+	return n.remove()
+}
+
+func (n *Network) remove() error {
+	if Debug {
+		return nil
+	}
+	l := n.pool.log()
+	// Containers kept by KillRemove outlive the network, which docker refuses to remove while
+	// they are attached.
+	ids, err := runDockerCommand(l, "docker", "network", "inspect", "-f", "{{range $id, $c := .Containers}}{{$id}} {{end}}", n.Name).Output()
+	if err != nil {
+		return fmt.Errorf("Error inspecting network %s: %v", n.Name, err)
+	}
+	for _, id := range strings.Fields(string(ids)) {
+		l.Debugf("disconnecting container %s from network %s", id, n.Name)
+		if out, err := runDockerCommand(l, "docker", "network", "disconnect", "-f", n.Name, id).CombinedOutput(); err != nil {
+			return fmt.Errorf("Error disconnecting %s from network %s: %v: %s", id, n.Name, err, out)
+		}
+	}
+	out, err := runDockerCommand(l, "docker", "network", "rm", n.Name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Error removing network %s: %v: %s", n.Name, err, out)
+	}
+	return nil
+}
+
+// connect attaches the running container c to the network.
+func (n *Network) connect(c ContainerID, aliases []string) error {
+	args := []string{"network", "connect"}
+	for _, a := range aliases {
+		args = append(args, "--alias", a)
+	}
+	args = append(args, n.Name, string(c))
+	out, err := runDockerCommand(n.pool.log(), "docker", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Error connecting %s to network %s: %v: %s", c, n.Name, err, out)
+	}
+	return nil
+}
+
+// NetworkIP returns the IP address of the container on the network n.
+func (c *Container) NetworkIP(n *Network) (string, error) {
+	info, err := c.Inspect()
+	if err != nil {
+		return "", err
+	}
+	cn, ok := info.Networks[n.Name]
+	if !ok || cn.IPAddress == "" {
+		return "", fmt.Errorf("container %s is not attached to network %s", c.ContainerID, n.Name)
+	}
+	return cn.IPAddress, nil
+}
+
+// NetworkAddr returns the address other containers on the network n reach ContainerPort at.
+// It uses the first alias the container was given on n, or its IP address if it has none.
+func (c *Container) NetworkAddr(n *Network) (string, error) {
+	for _, a := range c.options.Networks {
+		if a.Network.Name == n.Name && len(a.Aliases) > 0 {
+			return net.JoinHostPort(a.Aliases[0], strconv.Itoa(c.ContainerPort)), nil
+		}
+	}
+	ip, err := c.NetworkIP(n)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(c.ContainerPort)), nil
+}
@@ -0,0 +1,39 @@
+package dockertest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemoveNetworkDisconnectsKeptContainers(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	fakeDocker(t, fmt.Sprintf(`echo "$@" >> %s
+case "$1 $2" in
+"network inspect") echo "abc123 def456 " ;;
+"network rm") echo dockertest-1 ;;
+esac
+`, calls))
+
+	p := &Pool{Logger: NopLogger}
+	n := &Network{Name: "dockertest-1", pool: p}
+	p.networks = []*Network{n}
+	if err := p.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"network inspect -f {{range $id, $c := .Containers}}{{$id}} {{end}} dockertest-1",
+		"network disconnect -f dockertest-1 abc123",
+		"network disconnect -f dockertest-1 def456",
+		"network rm dockertest-1",
+	}
+	if got := strings.TrimSpace(string(out)); got != strings.Join(expected, "\n") {
+		t.Errorf("expected calls\n%s\ngot\n%s", strings.Join(expected, "\n"), got)
+	}
+}
@@ -0,0 +1,235 @@
+package dockertest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RunOptions configures how a pool runs a container. It is filled in by RunOption functions.
+type RunOptions struct {
+	// Env holds KEY=value pairs passed with -e.
+	Env []string
+	// Cmd holds the arguments passed after the image name.
+	Cmd []string
+	// Ports are container ports published on random host ports in addition to the main port, see WithPorts.
+	Ports []int
+	// HostPorts fixes the host ports of container ports instead of choosing random ones, see WithHostPort.
+	HostPorts map[int]int
+	// Tag overrides the default tag of a registered service, see WithTag.
+	Tag string
+	// Labels are set on the container in addition to the labels dockertest uses itself.
+	Labels map[string]string
+	// ReuseKey enables reuse mode if not empty, see WithReuse.
+	ReuseKey string
+	// SharedKey shares the container between processes if not empty, see WithShared.
+	SharedKey string
+	// Networks are the user defined networks the container is attached to, see WithNetwork.
+	Networks []NetworkAttachment
+	// Mounts are the bind mounts, volumes and tmpfs mounted into the container.
+	Mounts []Mount
+	// Memory limits the memory in bytes, see WithMemory.
+	Memory int64
+	// CPUs limits the number of CPUs, see WithCPUs.
+	CPUs float64
+	// ShmSize is the size of /dev/shm in bytes, see WithShmSize.
+	ShmSize int64
+	// Ulimits are the resource limits, see WithUlimit.
+	Ulimits []Ulimit
+	// Privileged runs the container in privileged mode, see WithPrivileged.
+	Privileged bool
+	// CapAdd and CapDrop are the Linux capabilities added and dropped, see WithCapAdd and WithCapDrop.
+	CapAdd, CapDrop []string
+	// Sysctls are the namespaced kernel parameters, see WithSysctl.
+	Sysctls map[string]string
+	// Init runs an init process in the container, see WithInit.
+	Init bool
+	// RestartPolicy is the restart policy, see WithRestartPolicy.
+	RestartPolicy string
+	// Proxy puts a Proxy in front of the published port, see WithProxy.
+	Proxy bool
+	// Wait decides when the container is ready in addition to its port being reachable, see WithWait.
+	Wait WaitStrategy
+	// MaxWait is how long to wait for the container to become reachable. Defaults to 60 seconds.
+	MaxWait time.Duration
+}
+
+// RunOption modifies the options a container is run with. It returns an error if the
+// option is invalid, which makes Run fail before any docker command is executed.
+type RunOption func(*RunOptions) error
+
+// WithEnv adds KEY=value environment variables.
+func WithEnv(env ...string) RunOption {
+	return func(o *RunOptions) error {
+		o.Env = append(o.Env, env...)
+		return nil
+	}
+}
+
+// WithCmd sets the arguments passed to the container after the image name.
+func WithCmd(args ...string) RunOption {
+	return func(o *RunOptions) error {
+		o.Cmd = append(o.Cmd, args...)
+		return nil
+	}
+}
+
+// WithPorts publishes additional container ports on random host ports. Use Container.HostPort
+// to find out where they are reachable.
+func WithPorts(ports ...int) RunOption {
+	return func(o *RunOptions) error {
+		for _, p := range ports {
+			if p < 1 || p > 65535 {
+				return fmt.Errorf("invalid port %d", p)
+			}
+		}
+		o.Ports = append(o.Ports, ports...)
+		return nil
+	}
+}
+
+// WithHostPort publishes containerPort on hostPort instead of a random host port. This is
+// needed by services that advertise their own address to clients.
+func WithHostPort(containerPort, hostPort int) RunOption {
+	return func(o *RunOptions) error {
+		if containerPort < 1 || containerPort > 65535 || hostPort < 1 || hostPort > 65535 {
+			return fmt.Errorf("invalid port mapping %d:%d", hostPort, containerPort)
+		}
+		if o.HostPorts == nil {
+			o.HostPorts = map[int]int{}
+		}
+		o.HostPorts[containerPort] = hostPort
+		return nil
+	}
+}
+
+// WithLabel sets a label on the container.
+func WithLabel(key, value string) RunOption {
+	return func(o *RunOptions) error {
+		if key == "" {
+			return fmt.Errorf("label key must not be empty")
+		}
+		if o.Labels == nil {
+			o.Labels = map[string]string{}
+		}
+		o.Labels[key] = value
+		return nil
+	}
+}
+
+// WithMaxWait sets how long to wait for the container to become reachable.
+func WithMaxWait(d time.Duration) RunOption {
+	return func(o *RunOptions) error {
+		if d <= 0 {
+			return fmt.Errorf("max wait must be positive, got %v", d)
+		}
+		o.MaxWait = d
+		return nil
+	}
+}
+
+// WithReuse enables reuse mode. The container gets a deterministic name derived from the image,
+// the options and key. If a running container with that name already exists and is reachable,
+// it is reattached instead of creating a new one, and KillRemove leaves it running for the next
+// test run. Use ForceKillRemove to get rid of it.
+func WithReuse(key string) RunOption {
+	return func(o *RunOptions) error {
+		if key == "" {
+			return fmt.Errorf("reuse key must not be empty")
+		}
+		o.ReuseKey = key
+		return nil
+	}
+}
+
+func newRunOptions(opts []RunOption) (*RunOptions, error) {
+	o := &RunOptions{MaxWait: 60 * time.Second}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, fmt.Errorf("invalid run option: %v", err)
+		}
+	}
+	return o, nil
+}
+
+// runArgs returns the arguments for "docker run" to run image as a container called name,
+// publishing the container ports in ports on the host ports they map to.
+func (o *RunOptions) runArgs(name, image string, ports map[int]int) []string {
+	args := []string{"--name", name, "-d"}
+	if len(ports) > 0 {
+		args = append(args, "-P")
+	}
+	containerPorts := make([]int, 0, len(ports))
+	for p := range ports {
+		containerPorts = append(containerPorts, p)
+	}
+	sort.Ints(containerPorts)
+	for _, p := range containerPorts {
+		forward := fmt.Sprintf("%d:%d", ports[p], p)
+		if BindDockerToLocalhost != "" {
+			forward = "127.0.0.1:" + forward
+		}
+		args = append(args, "-p", forward)
+	}
+	for _, e := range o.Env {
+		args = append(args, "-e", e)
+	}
+	for _, m := range o.Mounts {
+		args = append(args, "--mount", m.arg())
+	}
+	args = append(args, o.resourceArgs()...)
+	if len(o.Networks) > 0 {
+		// docker run attaches a single network, the others are connected once it runs.
+		args = append(args, "--network", o.Networks[0].Network.Name)
+		for _, alias := range o.Networks[0].Aliases {
+			args = append(args, "--network-alias", alias)
+		}
+	}
+	args = append(args, o.labelArgs()...)
+	args = append(args, image)
+	return append(args, o.Cmd...)
+}
+
+// run runs image with "docker run" and connects it to the networks docker run could not attach.
+func (o *RunOptions) run(l Logger, name, image string, ports map[int]int) (string, error) {
+	if err := o.checkSharedFolders(); err != nil {
+		return "", err
+	}
+	id, err := run(l, o.runArgs(name, image, ports)...)
+	if err != nil || len(o.Networks) < 2 {
+		return id, err
+	}
+	for _, n := range o.Networks[1:] {
+		if err := n.Network.connect(ContainerID(id), n.Aliases); err != nil {
+			forceRemove(l, ContainerID(id))
+			return "", err
+		}
+	}
+	return id, nil
+}
+
+// labelArgs returns the --label arguments for all labels, sorted by key.
+func (o *RunOptions) labelArgs() []string {
+	keys := make([]string, 0, len(o.Labels))
+	for k := range o.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var args []string
+	for _, k := range keys {
+		args = append(args, "--label", k+"="+o.Labels[k])
+	}
+	return args
+}
+
+// envValue returns the value of the last KEY=value pair for key in env.
+func envValue(env []string, key string) string {
+	value := ""
+	for _, e := range env {
+		if strings.HasPrefix(e, key+"=") {
+			value = strings.TrimPrefix(e, key+"=")
+		}
+	}
+	return value
+}
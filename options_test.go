@@ -0,0 +1,40 @@
+package dockertest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRunArgs(t *testing.T) {
+	backend, frontend := &Network{Name: "backend"}, &Network{Name: "frontend"}
+	o, err := newRunOptions([]RunOption{
+		WithEnv("A=1"),
+		WithLabel("b", "2"),
+		WithLabel("a", "1"),
+		WithNetwork(backend, "db", "postgres"),
+		WithNetwork(frontend),
+		WithCmd("-c", "fsync=off"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(bind string) { BindDockerToLocalhost = bind }(BindDockerToLocalhost)
+	BindDockerToLocalhost = ""
+	got := o.runArgs("name", "postgres", map[int]int{5432: 1234, 80: 8080})
+	want := []string{
+		"--name", "name", "-d", "-P", "-p", "8080:80", "-p", "1234:5432",
+		"-e", "A=1",
+		"--network", "backend", "--network-alias", "db", "--network-alias", "postgres",
+		"--label", "a=1", "--label", "b=2",
+		"postgres", "-c", "fsync=off",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestInvalidRunOption(t *testing.T) {
+	if _, err := RunContainer("redis", 6379, WithNetwork(nil)); err == nil {
+		t.Error("expected error for nil network")
+	}
+}
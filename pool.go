@@ -0,0 +1,160 @@
+package dockertest
+
+import (
+	"sync"
+
+	"github.com/pborman/uuid"
+)
+
+// Pool bundles the settings used to set up containers. The package level Setup functions use a
+// pool with default settings.
+type Pool struct {
+	// Logger receives the messages of this pool and the docker commands it runs. If nil, Log is used.
+	Logger Logger
+
+	mu         sync.Mutex
+	containers []*Container
+	networks   []*Network
+	volumes    []string
+}
+
+var defaultPool = &Pool{}
+
+// log returns the logger of the pool, falling back to the package logger.
+func (p *Pool) log() Logger {
+	if p.Logger != nil {
+		return p.Logger
+	}
+	return Log
+}
+
+// SetupContainer runs docker instance and returns port.
+func (p *Pool) SetupContainer(image string, containerPort int, args ...string) (c ContainerID, ip string, port int, err error) {
+	return p.SetupContainerWithEnv(image, containerPort, "", args...)
+}
+
+// SetupContainerWithEnv runs docker instance with env variable and returns port.
+func (p *Pool) SetupContainerWithEnv(image string, containerPort int, env string, args ...string) (c ContainerID, ip string, port int, err error) {
+	opts := []RunOption{WithCmd(args...)}
+	if env != "" {
+		opts = append(opts, WithEnv(env))
+	}
+	con, err := p.Run(image, containerPort, opts...)
+	if err != nil {
+		return "", "", 0, err
+	}
+	return con.ContainerID, con.Host, con.Port, nil
+}
+
+// Run runs image with the given options, publishes containerPort on a random host port and
+// waits until it is reachable.
+func (p *Pool) Run(image string, containerPort int, opts ...RunOption) (*Container, error) {
+	o, err := newRunOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	var c *Container
+	if o.SharedKey != "" {
+		c, err = p.runShared(image, containerPort, o)
+	} else {
+		c, err = p.start(image, containerPort, o)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if o.Proxy {
+		if err := c.startProxies(); err != nil {
+			c.KillRemove()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// start runs or, in reuse mode, reattaches the container described by o.
+func (p *Pool) start(image string, containerPort int, o *RunOptions) (*Container, error) {
+	l := p.log()
+	name := uuid.New()
+	if o.ReuseKey != "" {
+		hash := o.reuseHash(image, containerPort)
+		if c, err := p.reattach(image, containerPort, o, hash); err != nil {
+			return nil, err
+		} else if c != nil {
+			return c, nil
+		}
+		name = reuseNamePrefix + hash
+		o = o.withLabel(reuseLabel, hash)
+	}
+
+	l.Infof("setup container %s", image)
+	ports := map[int]int{}
+	for _, p := range append([]int{containerPort}, o.Ports...) {
+		if _, ok := ports[p]; ok {
+			continue
+		}
+		if hostPort, ok := o.HostPorts[p]; ok {
+			ports[p] = hostPort
+		} else {
+			ports[p] = randInt(1024, 49150)
+		}
+	}
+	port := ports[containerPort]
+	c, ip, err := setupContainer(l, image, port, o.MaxWait, func() (string, error) {
+		return o.run(l, name, image, ports)
+	})
+	if err != nil {
+		return nil, err
+	}
+	con := &Container{ContainerID: c, Image: image, Host: ip, Port: port, ContainerPort: containerPort, name: name, ports: ports, pool: p, options: o}
+	if err := con.waitUntilReady(); err != nil {
+		con.ForceKillRemove()
+		return nil, err
+	}
+	return p.track(con), nil
+}
+
+// track registers c to be removed by Purge.
+func (p *Pool) track(c *Container) *Container {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.containers = append(p.containers, c)
+	return c
+}
+
+// RunContainer runs image with the default pool, see Pool.Run.
+func RunContainer(image string, containerPort int, opts ...RunOption) (*Container, error) {
+	return defaultPool.Run(image, containerPort, opts...)
+}
+
+// KillRemove kills and removes the container, logging to the pool's logger.
+func (p *Pool) KillRemove(c ContainerID) error {
+	return c.killRemove(p.log())
+}
+
+// Purge calls KillRemove on all containers run by the pool and then removes the networks and
+// volumes it created. Volumes still used by containers KillRemove kept, for reuse or for other
+// processes, are kept too. It returns the first error, but tries to remove everything.
+func (p *Pool) Purge() error {
+	p.mu.Lock()
+	containers, networks, volumes := p.containers, p.networks, p.volumes
+	p.containers, p.networks, p.volumes = nil, nil, nil
+	p.mu.Unlock()
+
+	var first error
+	for i := len(containers) - 1; i >= 0; i-- {
+		if err := containers[i].KillRemove(); err != nil && first == nil {
+			first = err
+		}
+	}
+	for _, n := range networks {
+		if err := n.remove(); err != nil && first == nil {
+			first = err
+		}
+	}
+	for _, v := range volumes {
+		if err := removeUnusedVolume(p.log(), v); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
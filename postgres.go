@@ -0,0 +1,127 @@
+package dockertest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// PostgreSQLContainer is a container running PostgreSQL.
+type PostgreSQLContainer struct {
+	*Container
+}
+
+// RunPostgreSQL runs a PostgreSQL container with the default pool, see Pool.RunPostgreSQL.
+func RunPostgreSQL(opts ...RunOption) (*PostgreSQLContainer, error) {
+	return defaultPool.RunPostgreSQL(opts...)
+}
+
+// RunPostgreSQL runs a PostgreSQL container. The password of PostgresUsername is PostgresPassword.
+func (p *Pool) RunPostgreSQL(opts ...RunOption) (*PostgreSQLContainer, error) {
+	c, err := p.RunService("postgres", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &PostgreSQLContainer{c}, nil
+}
+
+// DSN returns the connection URL of the given database.
+func (c *PostgreSQLContainer) DSN(database string) string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(PostgresUsername, PostgresPassword),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + database,
+		RawQuery: "sslmode=disable",
+	}
+	return u.String()
+}
+
+// Query runs sql with psql inside the container, connected to the given database, and returns
+// the unaligned output without headers.
+func (c *PostgreSQLContainer) Query(database, sql string) (string, error) {
+	out, err := c.Exec("psql", "-U", PostgresUsername, "-d", database, "-v", "ON_ERROR_STOP=1", "-At", "-c", sql)
+	return strings.TrimSpace(string(out)), err
+}
+
+// CreateDatabase creates the database name. If template is not empty, the database is created as a
+// copy of the template database.
+func (c *PostgreSQLContainer) CreateDatabase(name, template string) error {
+	sql := fmt.Sprintf(`CREATE DATABASE "%s"`, name)
+	if template != "" {
+		sql += fmt.Sprintf(` TEMPLATE "%s"`, template)
+	}
+	_, err := c.Query("postgres", sql)
+	return err
+}
+
+// DropDatabase terminates all connections to the database name and drops it.
+func (c *PostgreSQLContainer) DropDatabase(name string) error {
+	if _, err := c.Query("postgres", fmt.Sprintf(`SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = '%s'`, name)); err != nil {
+		return err
+	}
+	_, err := c.Query("postgres", fmt.Sprintf(`DROP DATABASE IF EXISTS "%s"`, name))
+	return err
+}
+
+// NewDatabase creates a uniquely named database for the test and drops it when the test finishes.
+// It returns the DSN of the new database.
+func (c *PostgreSQLContainer) NewDatabase(tb testing.TB) string {
+	return c.NewDatabaseFromTemplate(tb, "")
+}
+
+// NewDatabaseFromTemplate is like NewDatabase, but the new database is a copy of template,
+// typically prepared with PrepareTemplate.
+func (c *PostgreSQLContainer) NewDatabaseFromTemplate(tb testing.TB, template string) string {
+	tb.Helper()
+	name := uniqueDatabaseName(tb)
+	if err := c.CreateDatabase(name, template); err != nil {
+		tb.Fatalf("Could not create database: %v", err)
+	}
+	tb.Cleanup(func() {
+		if err := c.DropDatabase(name); err != nil {
+			tb.Errorf("Could not drop database %s: %v", name, err)
+		}
+	})
+	return c.DSN(name)
+}
+
+// PrepareTemplate creates the database name and calls setup with its DSN, for example to run
+// migrations, unless the database already exists. Setup must close all of its connections
+// before returning, because PostgreSQL refuses to copy a database that is in use.
+// Processes sharing the container prepare the template only once.
+func (c *PostgreSQLContainer) PrepareTemplate(name string, setup func(dsn string) error) error {
+	h := sha256.Sum256([]byte(string(c.ContainerID) + "/" + name))
+	lock, err := lockFile(filepath.Join(os.TempDir(), "dockertest-template-"+hex.EncodeToString(h[:])[:16]+".lock"))
+	if err != nil {
+		return fmt.Errorf("Error locking template %s: %v", name, err)
+	}
+	defer unlockFile(lock)
+
+	exists, err := c.Query("postgres", fmt.Sprintf(`SELECT 1 FROM pg_database WHERE datname = '%s' AND datistemplate`, name))
+	if err != nil {
+		return err
+	}
+	if exists == "1" {
+		return nil
+	}
+	// A database left behind by a failed setup is not marked as template yet.
+	if err := c.DropDatabase(name); err != nil {
+		return err
+	}
+	if err := c.CreateDatabase(name, ""); err != nil {
+		return err
+	}
+	if err := setup(c.DSN(name)); err != nil {
+		return fmt.Errorf("Error setting up template %s: %v", name, err)
+	}
+	_, err = c.Query("postgres", fmt.Sprintf(`UPDATE pg_database SET datistemplate = true WHERE datname = '%s'`, name))
+	return err
+}
@@ -0,0 +1,336 @@
+package dockertest
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Proxy is a TCP proxy between the test and a container that injects faults on demand, like
+// latency, limited bandwidth and connection resets, and counts the bytes of every connection.
+type Proxy struct {
+	ln     net.Listener
+	target func() string
+	log    Logger
+
+	mu        sync.Mutex
+	latency   time.Duration
+	bandwidth int64
+	conns     map[*proxyConn]struct{}
+	history   []*proxyConn
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// proxyConn is a client connection and the connection to the target it is forwarded to.
+type proxyConn struct {
+	client, upstream net.Conn
+	opened           time.Time
+	// sent and received are accessed atomically.
+	sent, received int64
+	// closed is guarded by the proxy's mutex.
+	closed bool
+}
+
+// ConnStats describes a connection a proxy forwarded.
+type ConnStats struct {
+	// Client is the address of the client.
+	Client string
+	// Opened is the time the connection was accepted.
+	Opened time.Time
+	// Closed is true once the connection has been closed.
+	Closed bool
+	// Sent is the number of bytes forwarded from the client to the target.
+	Sent int64
+	// Received is the number of bytes forwarded from the target to the client.
+	Received int64
+}
+
+// NewProxy returns a proxy listening on a random port of 127.0.0.1 that forwards connections to target.
+func NewProxy(target string) (*Proxy, error) {
+	return newProxy(Log, func() string { return target })
+}
+
+// WithProxy puts a Proxy in front of every published port of the container when it is run,
+// see Container.ProxyPort.
+func WithProxy() RunOption {
+	return func(o *RunOptions) error {
+		o.Proxy = true
+		return nil
+	}
+}
+
+// Proxy returns the proxy forwarding connections to the container's published port, see ProxyPort.
+func (c *Container) Proxy() (*Proxy, error) {
+	return c.ProxyPort(c.ContainerPort)
+}
+
+// ProxyPort returns the proxy forwarding connections to the host port containerPort is
+// published on, starting it on the first call unless the container was run WithProxy. The
+// proxy looks up the address for every connection, so its address stays the same when the
+// container is restarted or restored. It is closed when the container is removed.
+func (c *Container) ProxyPort(containerPort int) (*Proxy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.proxies[containerPort]; ok {
+		return p, nil
+	}
+	if _, ok := c.ports[containerPort]; !ok {
+		return nil, fmt.Errorf("port %d of %s is not published", containerPort, c.ContainerID)
+	}
+	p, err := newProxy(c.pool.log(), func() string { return c.proxyTarget(containerPort) })
+	if err != nil {
+		return nil, err
+	}
+	if c.proxies == nil {
+		c.proxies = map[int]*Proxy{}
+	}
+	c.proxies[containerPort] = p
+	return p, nil
+}
+
+// startProxies starts a proxy for every published port.
+func (c *Container) startProxies() error {
+	ports := make([]int, 0, len(c.ports))
+	for p := range c.ports {
+		ports = append(ports, p)
+	}
+	sort.Ints(ports)
+	for _, p := range ports {
+		if _, err := c.ProxyPort(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// proxyTarget returns the current address of containerPort on the host.
+func (c *Container) proxyTarget(containerPort int) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.ports[containerPort]))
+}
+
+// closeProxies closes the proxies started by ProxyPort, if any.
+func (c *Container) closeProxies() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range c.proxies {
+		p.Close()
+	}
+	c.proxies = nil
+}
+
+func newProxy(l Logger, target func() string) (*Proxy, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("Error starting proxy: %v", err)
+	}
+	p := &Proxy{ln: ln, target: target, log: l, conns: map[*proxyConn]struct{}{}}
+	p.wg.Add(1)
+	go p.serve()
+	return p, nil
+}
+
+// Addr returns the address clients connect to instead of the target.
+func (p *Proxy) Addr() string {
+	return p.ln.Addr().String()
+}
+
+// SetLatency delays all data forwarded in either direction by d.
+func (p *Proxy) SetLatency(d time.Duration) {
+	p.mu.Lock()
+	p.latency = d
+	p.mu.Unlock()
+}
+
+// SetBandwidth limits the data forwarded per connection and direction to bytesPerSecond.
+// Zero removes the limit.
+func (p *Proxy) SetBandwidth(bytesPerSecond int64) {
+	p.mu.Lock()
+	p.bandwidth = bytesPerSecond
+	p.mu.Unlock()
+}
+
+// Stats returns the connections the proxy forwarded so far, in the order they were accepted.
+func (p *Proxy) Stats() []ConnStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]ConnStats, len(p.history))
+	for i, c := range p.history {
+		stats[i] = ConnStats{
+			Client:   c.client.RemoteAddr().String(),
+			Opened:   c.opened,
+			Closed:   c.closed,
+			Sent:     atomic.LoadInt64(&c.sent),
+			Received: atomic.LoadInt64(&c.received),
+		}
+	}
+	return stats
+}
+
+// takeConns removes all open connections from the proxy and returns them.
+func (p *Proxy) takeConns() map[*proxyConn]struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conns := p.conns
+	p.conns = map[*proxyConn]struct{}{}
+	for c := range conns {
+		c.closed = true
+	}
+	return conns
+}
+
+// ResetConnections closes all open connections with a TCP reset. New connections are accepted.
+func (p *Proxy) ResetConnections() {
+	for c := range p.takeConns() {
+		c.reset()
+	}
+}
+
+// CloseConnections closes all open connections normally, as a server shutting down would.
+// New connections are accepted.
+func (p *Proxy) CloseConnections() {
+	for c := range p.takeConns() {
+		c.client.Close()
+		c.upstream.Close()
+	}
+}
+
+// Close stops accepting connections, resets all open ones and waits until they are closed.
+func (p *Proxy) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	err := p.ln.Close()
+	p.ResetConnections()
+	p.wg.Wait()
+	return err
+}
+
+func (p *Proxy) serve() {
+	defer p.wg.Done()
+	for {
+		client, err := p.ln.Accept()
+		if err != nil {
+			p.mu.Lock()
+			closed := p.closed
+			p.mu.Unlock()
+			if !closed {
+				p.log.Warnf("proxy %s stopped accepting connections: %v", p.Addr(), err)
+			}
+			return
+		}
+		p.wg.Add(1)
+		go p.handle(client)
+	}
+}
+
+func (p *Proxy) handle(client net.Conn) {
+	defer p.wg.Done()
+	target := p.target()
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		p.log.Debugf("proxy %s could not reach %s: %v", p.Addr(), target, err)
+		client.Close()
+		return
+	}
+	c := &proxyConn{client: client, upstream: upstream, opened: time.Now()}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		c.reset()
+		return
+	}
+	p.conns[c] = struct{}{}
+	p.history = append(p.history, c)
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		p.forward(upstream, client, &c.sent)
+	}()
+	go func() {
+		defer wg.Done()
+		p.forward(client, upstream, &c.received)
+	}()
+	wg.Wait()
+
+	p.mu.Lock()
+	delete(p.conns, c)
+	c.closed = true
+	p.mu.Unlock()
+	client.Close()
+	upstream.Close()
+}
+
+// proxySettings are the faults a proxy currently injects.
+type proxySettings struct {
+	latency   time.Duration
+	bandwidth int64
+}
+
+func (p *Proxy) settings() proxySettings {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return proxySettings{latency: p.latency, bandwidth: p.bandwidth}
+}
+
+// forward copies data from src to dst, applying the current latency and bandwidth settings,
+// and adds the number of bytes forwarded to count.
+func (p *Proxy) forward(dst, src net.Conn, count *int64) {
+	buf := make([]byte, 32*1024)
+	for {
+		chunk := buf
+		if bandwidth := p.settings().bandwidth; bandwidth > 0 && bandwidth < int64(len(chunk)) {
+			chunk = buf[:bandwidth]
+		}
+		n, err := src.Read(chunk)
+		if n > 0 {
+			// Settings are read again, as they may have changed while waiting for data.
+			s := p.settings()
+			if s.latency > 0 {
+				time.Sleep(s.latency)
+			}
+			written, werr := dst.Write(chunk[:n])
+			atomic.AddInt64(count, int64(written))
+			if werr != nil {
+				src.Close()
+				return
+			}
+			if s.bandwidth > 0 {
+				time.Sleep(time.Duration(int64(n) * int64(time.Second) / s.bandwidth))
+			}
+		}
+		if err == io.EOF {
+			closeWrite(dst)
+			return
+		} else if err != nil {
+			dst.Close()
+			return
+		}
+	}
+}
+
+// reset closes both connections so that their peers see a TCP reset.
+func (c *proxyConn) reset() {
+	for _, conn := range []net.Conn{c.client, c.upstream} {
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			tcp.SetLinger(0)
+		}
+		conn.Close()
+	}
+}
+
+func closeWrite(c net.Conn) {
+	if tcp, ok := c.(*net.TCPConn); ok {
+		tcp.CloseWrite()
+	}
+}
@@ -0,0 +1,228 @@
+package dockertest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// echoServer returns the address of a server echoing everything back.
+func echoServer(t *testing.T) string {
+	return echoListener(t, "127.0.0.1:0").Addr().String()
+}
+
+// echoListener starts a server on addr echoing everything back until the listener is closed.
+func echoListener(t *testing.T, addr string) net.Listener {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(c, c)
+				c.Close()
+			}()
+		}
+	}()
+	return ln
+}
+
+func roundTrip(t *testing.T, c net.Conn) time.Duration {
+	start := time.Now()
+	if _, err := c.Write([]byte("ping\n")); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(c).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "ping\n" {
+		t.Fatalf("unexpected echo %q", line)
+	}
+	return time.Since(start)
+}
+
+func TestProxy(t *testing.T) {
+	p, err := NewProxy(echoServer(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	c, err := net.Dial("tcp", p.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	roundTrip(t, c)
+
+	p.SetLatency(50 * time.Millisecond)
+	if d := roundTrip(t, c); d < 100*time.Millisecond {
+		t.Errorf("round trip took %v despite latency", d)
+	}
+	p.SetLatency(0)
+
+	p.ResetConnections()
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := c.Read(make([]byte, 1)); err == nil {
+		t.Error("connection still open after reset")
+	}
+
+	c2, err := net.Dial("tcp", p.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	roundTrip(t, c2)
+	roundTrip(t, c2)
+
+	p.CloseConnections()
+	c2.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := c2.Read(make([]byte, 1)); err == nil {
+		t.Error("connection still open after close")
+	}
+	stats := p.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 connections, got %+v", stats)
+	}
+	if s := stats[1]; !s.Closed || s.Sent != 10 || s.Received != 10 || s.Client != c2.LocalAddr().String() {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+// hostPort returns the port of the listener ln.
+func hostPort(ln net.Listener) int {
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestContainerProxyConcurrent(t *testing.T) {
+	c := &Container{Host: "127.0.0.1", Port: 1, ContainerPort: 80, ports: map[int]int{80: 1}, pool: &Pool{Logger: NopLogger}}
+	defer c.closeProxies()
+	proxies := make([]*Proxy, 10)
+	var wg sync.WaitGroup
+	for i := range proxies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p, err := c.Proxy()
+			if err != nil {
+				t.Error(err)
+			}
+			proxies[i] = p
+		}(i)
+	}
+	wg.Wait()
+	for _, p := range proxies {
+		if p != proxies[0] {
+			t.Fatal("Proxy started more than one proxy")
+		}
+	}
+}
+
+func TestContainerProxyPort(t *testing.T) {
+	main, extra := echoListener(t, "127.0.0.1:0"), echoListener(t, "127.0.0.1:0")
+	c := &Container{Host: "127.0.0.1", Port: hostPort(main), ContainerPort: 80,
+		ports: map[int]int{80: hostPort(main), 81: hostPort(extra)}, pool: &Pool{Logger: NopLogger}}
+	defer c.closeProxies()
+	if err := c.startProxies(); err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.Proxy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pe, err := c.ProxyPort(81)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == pe || len(c.proxies) != 2 {
+		t.Fatalf("expected a proxy per port, got %v", c.proxies)
+	}
+	conn, err := net.Dial("tcp", pe.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	roundTrip(t, conn)
+	if _, err := c.ProxyPort(82); err == nil {
+		t.Error("expected an error for a port that is not published")
+	}
+}
+
+func TestContainerProxyRestart(t *testing.T) {
+	before := echoListener(t, "127.0.0.1:0")
+	port := hostPort(before)
+	// The restarted container has a new IP, but keeps its host port.
+	after, err := net.Listen("tcp", fmt.Sprintf("127.0.0.2:%d", port))
+	if err != nil {
+		t.Skipf("127.0.0.2 is not available: %v", err)
+	}
+	after.Close()
+	fakeDocker(t, `case "$1 $2" in
+"inspect -f") echo 2026-01-02T03:04:05.123456789Z ;;
+inspect*) echo '[{"NetworkSettings": {"IPAddress": "127.0.0.2"}}]' ;;
+esac
+`)
+	bind := BindDockerToLocalhost
+	BindDockerToLocalhost = ""
+	defer func() { BindDockerToLocalhost = bind }()
+
+	c := &Container{ContainerID: "abc123", Host: "127.0.0.1", Port: port, ContainerPort: 80, ports: map[int]int{80: port},
+		pool: &Pool{Logger: NopLogger}, options: &RunOptions{MaxWait: 5 * time.Second}}
+	defer c.closeProxies()
+	p, err := c.Proxy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("tcp", p.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundTrip(t, conn)
+	conn.Close()
+
+	// Clients keep connecting while the container restarts.
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if conn, err := net.Dial("tcp", p.Addr()); err == nil {
+				conn.Close()
+			}
+		}
+	}()
+	before.Close()
+	echoListener(t, fmt.Sprintf("127.0.0.2:%d", port))
+	err = c.Restart()
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Host != "127.0.0.2" {
+		t.Fatalf("host not updated: %s", c.Host)
+	}
+	conn, err = net.Dial("tcp", p.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	roundTrip(t, conn)
+}
@@ -0,0 +1,70 @@
+package dockertest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeRabbitMQ returns a RabbitMQContainer whose management port is served by h.
+func fakeRabbitMQ(t *testing.T, h http.Handler) (*RabbitMQContainer, func()) {
+	srv := httptest.NewServer(h)
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	p, _ := strconv.Atoi(port)
+	c := &Container{Host: host, Port: 32768, ports: map[int]int{rabbitmqPort: 32768, rabbitmqManagementPort: p},
+		options: &RunOptions{Env: []string{"RABBITMQ_DEFAULT_USER=user", "RABBITMQ_DEFAULT_PASS=p@ss"}}}
+	return &RabbitMQContainer{c}, srv.Close
+}
+
+func TestRabbitMQAMQPURL(t *testing.T) {
+	c, done := fakeRabbitMQ(t, http.NotFoundHandler())
+	defer done()
+	for vhost, suffix := range map[string]string{"": "/", "orders": "/orders", "/": "/%2F", "a/b": "/a%2Fb"} {
+		expected := "amqp://user:p%40ss@" + c.Host + ":32768" + suffix
+		if u := c.AMQPURL(vhost); u != expected {
+			t.Errorf("%q: expected %s, got %s", vhost, expected, u)
+		}
+	}
+}
+
+func TestRabbitMQLoadDefinitions(t *testing.T) {
+	var requests []string
+	c, done := fakeRabbitMQ(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "user" || pass != "p@ss" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+		if r.URL.Path == "/api/definitions" {
+			var defs map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&defs); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		}
+	}))
+	defer done()
+
+	path := filepath.Join(t.TempDir(), "definitions.json")
+	defs := `{"vhosts": [{"name": "orders"}, {"name": "/"}], "queues": [{"name": "q", "vhost": "orders"}]}`
+	if err := ioutil.WriteFile(path, []byte(defs), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.LoadDefinitions(path); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"POST /api/definitions",
+		"PUT /api/permissions/orders/user",
+		"PUT /api/permissions/%2F/user",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected requests\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(requests, "\n"))
+	}
+}
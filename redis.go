@@ -0,0 +1,356 @@
+package dockertest
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RedisContainer is a container running Redis.
+type RedisContainer struct {
+	*Container
+}
+
+// RunRedis runs a Redis container with the default pool, see Pool.RunRedis.
+func RunRedis(opts ...RunOption) (*RedisContainer, error) {
+	return defaultPool.RunRedis(opts...)
+}
+
+// RunRedis runs a Redis container.
+func (p *Pool) RunRedis(opts ...RunOption) (*RedisContainer, error) {
+	c, err := p.RunService("redis", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisContainer{c}, nil
+}
+
+// CLI runs redis-cli with the given arguments inside the container and returns its output.
+// Error replies are returned as error.
+func (c *RedisContainer) CLI(args ...string) (string, error) {
+	return c.cli(c.ContainerPort, args...)
+}
+
+// cli runs redis-cli against the server listening on port inside the container.
+func (c *RedisContainer) cli(port int, args ...string) (string, error) {
+	out, err := c.Exec(append([]string{"redis-cli", "-p", strconv.Itoa(port)}, args...)...)
+	reply := strings.TrimSpace(string(out))
+	if err == nil && (strings.HasPrefix(reply, "ERR") || strings.HasPrefix(reply, "(error)")) {
+		err = fmt.Errorf("Error running redis-cli %s: %s", strings.Join(args, " "), reply)
+	}
+	return reply, err
+}
+
+// RedisClusterContainer is a container running the nodes of a Redis Cluster. All nodes run in
+// the same container, each listening on the port it is published on, so that the addresses the
+// nodes announce in MOVED and ASK redirections are reachable from the host.
+type RedisClusterContainer struct {
+	*RedisContainer
+
+	masters []int
+	nodes   []int
+}
+
+// RunRedisCluster runs a Redis Cluster with the default pool, see Pool.RunRedisCluster.
+func RunRedisCluster(masters, replicas int, opts ...RunOption) (*RedisClusterContainer, error) {
+	return defaultPool.RunRedisCluster(masters, replicas, opts...)
+}
+
+// RunRedisCluster runs a Redis Cluster of masters masters, each with replicas replicas, and
+// assigns the hash slots evenly to the masters. Redis requires at least 3 masters. It returns
+// once all nodes report cluster_state:ok. The cluster cannot be reused or shared.
+func (p *Pool) RunRedisCluster(masters, replicas int, opts ...RunOption) (*RedisClusterContainer, error) {
+	if masters < 3 {
+		return nil, fmt.Errorf("a redis cluster needs at least 3 masters, got %d", masters)
+	}
+	if replicas < 0 {
+		return nil, fmt.Errorf("replicas must not be negative, got %d", replicas)
+	}
+	host, err := advertisedHost(p.log())
+	if err != nil {
+		return nil, err
+	}
+	nodes := redisPorts(masters * (1 + replicas))
+	var cmds [][]string
+	var published []int
+	for _, port := range nodes {
+		cmds = append(cmds, []string{
+			"redis-server", "--port", strconv.Itoa(port), "--protected-mode", "no",
+			"--cluster-enabled", "yes", "--cluster-config-file", fmt.Sprintf("nodes-%d.conf", port),
+			"--cluster-announce-ip", host,
+		})
+		// The nodes gossip on the bus port, which is published for docker-machine, where they
+		// reach each other through the address of the VM.
+		published = append(published, port, port+10000)
+	}
+	c, err := p.runRedisGroup(published, nil, cmds, opts)
+	if err != nil {
+		return nil, err
+	}
+	rc := &RedisClusterContainer{RedisContainer: c, masters: nodes[:masters], nodes: nodes}
+
+	err = retry(c.options.MaxWait, func() error {
+		for _, port := range nodes {
+			if _, err := rc.cli(port, "ping"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.ForceKillRemove()
+		return nil, fmt.Errorf("redis cluster nodes are not reachable: %v", err)
+	}
+	// redis-cli assigns the first nodes given as masters and the rest as their replicas.
+	args := []string{"--cluster", "create"}
+	for _, port := range nodes {
+		args = append(args, net.JoinHostPort(host, strconv.Itoa(port)))
+	}
+	args = append(args, "--cluster-replicas", strconv.Itoa(replicas), "--cluster-yes")
+	if _, err := rc.cli(nodes[0], args...); err != nil {
+		c.ForceKillRemove()
+		return nil, err
+	}
+	err = retry(c.options.MaxWait, func() error {
+		for _, port := range nodes {
+			out, err := rc.cli(port, "cluster", "info")
+			if err != nil {
+				return err
+			}
+			if !strings.Contains(out, "cluster_state:ok") {
+				return fmt.Errorf("cluster state of node %d is not ok", port)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.ForceKillRemove()
+		return nil, fmt.Errorf("redis cluster is not ready: %v", err)
+	}
+	return rc, nil
+}
+
+// Addrs returns the host:port addresses of all nodes, masters first. Cluster clients need only
+// one of them to discover the others.
+func (c *RedisClusterContainer) Addrs() []string {
+	return c.addrs(c.nodes)
+}
+
+// MasterAddrs returns the host:port addresses of the nodes started as masters.
+func (c *RedisClusterContainer) MasterAddrs() []string {
+	return c.addrs(c.masters)
+}
+
+// NodeCLI runs redis-cli against the node i of Addrs. Pass "-c" first to follow redirections.
+func (c *RedisClusterContainer) NodeCLI(i int, args ...string) (string, error) {
+	if i < 0 || i >= len(c.nodes) {
+		return "", fmt.Errorf("no redis cluster node %d", i)
+	}
+	return c.cli(c.nodes[i], args...)
+}
+
+// RedisSentinelContainer is a container running a Redis master, its replicas and the Sentinels
+// monitoring them. Like RedisClusterContainer, all processes run in the same container and
+// announce addresses reachable from the host.
+type RedisSentinelContainer struct {
+	*RedisContainer
+
+	name      string
+	replicas  []int
+	sentinels []int
+}
+
+// RunRedisSentinel runs a Redis Sentinel topology with the default pool, see Pool.RunRedisSentinel.
+func RunRedisSentinel(name string, replicas, sentinels int, opts ...RunOption) (*RedisSentinelContainer, error) {
+	return defaultPool.RunRedisSentinel(name, replicas, sentinels, opts...)
+}
+
+// RunRedisSentinel runs a master monitored under name, with replicas replicas and sentinels
+// Sentinels agreeing on failover by majority. The container's Addr is the address of the
+// initial master. It returns once every Sentinel knows the master, its replicas and the other
+// Sentinels. The topology cannot be reused or shared.
+func (p *Pool) RunRedisSentinel(name string, replicas, sentinels int, opts ...RunOption) (*RedisSentinelContainer, error) {
+	if name == "" {
+		return nil, fmt.Errorf("master name must not be empty")
+	}
+	if replicas < 0 || sentinels < 1 {
+		return nil, fmt.Errorf("need at least one sentinel and no negative number of replicas, got %d sentinels and %d replicas", sentinels, replicas)
+	}
+	host, err := advertisedHost(p.log())
+	if err != nil {
+		return nil, err
+	}
+	ports := redisPorts(1 + replicas + sentinels)
+	master := strconv.Itoa(ports[0])
+	cmds := [][]string{{"redis-server", "--port", master, "--protected-mode", "no"}}
+	for _, port := range ports[1 : 1+replicas] {
+		cmds = append(cmds, []string{
+			"redis-server", "--port", strconv.Itoa(port), "--protected-mode", "no",
+			"--replicaof", host, master, "--replica-announce-ip", host,
+		})
+	}
+	files := map[string][]string{}
+	for _, port := range ports[1+replicas:] {
+		// Sentinel rewrites its configuration, so each one gets a file of its own.
+		conf := fmt.Sprintf("sentinel-%d.conf", port)
+		files[conf] = []string{
+			fmt.Sprintf("port %d", port),
+			"protected-mode no",
+			fmt.Sprintf("sentinel monitor %s %s %s %d", name, host, master, sentinels/2+1),
+			"sentinel announce-ip " + host,
+			fmt.Sprintf("sentinel down-after-milliseconds %s 1000", name),
+			fmt.Sprintf("sentinel failover-timeout %s 5000", name),
+		}
+		cmds = append(cmds, []string{"redis-sentinel", conf})
+	}
+	c, err := p.runRedisGroup(ports, files, cmds, opts)
+	if err != nil {
+		return nil, err
+	}
+	rs := &RedisSentinelContainer{RedisContainer: c, name: name, replicas: ports[1 : 1+replicas], sentinels: ports[1+replicas:]}
+	err = retry(c.options.MaxWait, func() error {
+		for _, port := range rs.sentinels {
+			out, err := rs.cli(port, "sentinel", "master", name)
+			if err != nil {
+				return err
+			}
+			m := redisFields(out)
+			if m["flags"] != "master" {
+				return fmt.Errorf("sentinel %d reports master flags %q", port, m["flags"])
+			}
+			if m["num-slaves"] != strconv.Itoa(replicas) || m["num-other-sentinels"] != strconv.Itoa(sentinels-1) {
+				return fmt.Errorf("sentinel %d knows %s replicas and %s other sentinels", port, m["num-slaves"], m["num-other-sentinels"])
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.ForceKillRemove()
+		return nil, fmt.Errorf("redis sentinel is not ready: %v", err)
+	}
+	return rs, nil
+}
+
+// MasterName returns the name the master is monitored under.
+func (c *RedisSentinelContainer) MasterName() string {
+	return c.name
+}
+
+// MasterAddr asks the Sentinels for the host:port address of the current master, which changes
+// after a failover.
+func (c *RedisSentinelContainer) MasterAddr() (string, error) {
+	out, err := c.SentinelCLI("sentinel", "get-master-addr-by-name", c.name)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return "", fmt.Errorf("no master known for %s: %s", c.name, out)
+	}
+	return net.JoinHostPort(fields[0], fields[1]), nil
+}
+
+// ReplicaAddrs returns the host:port addresses of the replicas.
+func (c *RedisSentinelContainer) ReplicaAddrs() []string {
+	return c.addrs(c.replicas)
+}
+
+// SentinelAddrs returns the host:port addresses of the Sentinels.
+func (c *RedisSentinelContainer) SentinelAddrs() []string {
+	return c.addrs(c.sentinels)
+}
+
+// SentinelCLI runs redis-cli against the first Sentinel.
+func (c *RedisSentinelContainer) SentinelCLI(args ...string) (string, error) {
+	return c.cli(c.sentinels[0], args...)
+}
+
+// runRedisGroup runs cmds in the background of a single redis container, after writing the
+// lines of files to the data directory. Every port in ports is published on the same host
+// port, the first one being the container's main port. As the ports differ between runs, the
+// group cannot be reused or shared with WithReuse or WithShared.
+func (p *Pool) runRedisGroup(ports []int, files map[string][]string, cmds [][]string, opts []RunOption) (*RedisContainer, error) {
+	s, ok := Lookup("redis")
+	if !ok {
+		return nil, fmt.Errorf("unknown service redis")
+	}
+	o, err := newRunOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if o.ReuseKey != "" || o.SharedKey != "" {
+		return nil, fmt.Errorf("redis clusters and sentinels do not support WithReuse or WithShared")
+	}
+	all := []RunOption{WithPorts(ports[1:]...), WithCmd("sh", "-c", redisScript(files, cmds))}
+	for _, port := range ports {
+		all = append(all, WithHostPort(port, port))
+	}
+	c, err := p.runService(s, ports[0], append(all, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisContainer{c}, nil
+}
+
+// redisScript returns a shell script writing files and running cmds in the background. It waits
+// for all of them, so that the container keeps running when single processes are shut down.
+func redisScript(files map[string][]string, cmds [][]string) string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var lines []string
+	for _, name := range names {
+		lines = append(lines, "printf '%s\\n' "+shellJoin(files[name])+" > "+shellQuote(name))
+	}
+	for _, cmd := range cmds {
+		lines = append(lines, shellJoin(cmd)+" &")
+	}
+	return strings.Join(append(lines, "wait"), "\n")
+}
+
+// shellJoin quotes args and joins them to a shell command line.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// redisPorts returns n distinct random ports, leaving room for the cluster bus ports 10000 above.
+func redisPorts(n int) []int {
+	seen := map[int]bool{}
+	var ports []int
+	for len(ports) < n {
+		port := randInt(1024, 49150)
+		if seen[port] || seen[port+10000] || seen[port-10000] {
+			continue
+		}
+		seen[port] = true
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// redisFields parses the alternating field and value lines redis-cli prints for replies like
+// SENTINEL MASTER.
+func redisFields(out string) map[string]string {
+	lines := strings.Split(out, "\n")
+	fields := map[string]string{}
+	for i := 0; i+1 < len(lines); i += 2 {
+		fields[strings.TrimSpace(lines[i])] = strings.TrimSpace(lines[i+1])
+	}
+	return fields
+}
+
+func (c *RedisContainer) addrs(ports []int) []string {
+	addrs := make([]string, len(ports))
+	for i, port := range ports {
+		addrs[i] = c.PortAddr(port)
+	}
+	return addrs
+}
@@ -0,0 +1,53 @@
+package dockertest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedisScript(t *testing.T) {
+	script := redisScript(
+		map[string][]string{"sentinel-2.conf": {"port 2", "sentinel monitor m 127.0.0.1 1 1"}},
+		[][]string{{"redis-server", "--port", "1"}, {"redis-sentinel", "sentinel-2.conf"}},
+	)
+	expected := `printf '%s\n' 'port 2' 'sentinel monitor m 127.0.0.1 1 1' > sentinel-2.conf
+redis-server --port 1 &
+redis-sentinel sentinel-2.conf &
+wait`
+	if script != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, script)
+	}
+}
+
+func TestRedisPorts(t *testing.T) {
+	ports := redisPorts(20)
+	seen := map[int]bool{}
+	for _, p := range ports {
+		if seen[p] || seen[p+10000] || seen[p-10000] {
+			t.Errorf("port %d or its bus port is used twice", p)
+		}
+		if p+10000 > 65535 {
+			t.Errorf("bus port of %d is out of range", p)
+		}
+		seen[p] = true
+	}
+}
+
+func TestRedisFields(t *testing.T) {
+	fields := redisFields("name\nmymaster\nflags\nmaster\nnum-slaves\n1\n")
+	if fields["flags"] != "master" || fields["num-slaves"] != "1" || fields["name"] != "mymaster" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+}
+
+func TestRedisGroupRejectsReuse(t *testing.T) {
+	p := &Pool{Logger: NopLogger}
+	for name, opt := range map[string]RunOption{"reuse": WithReuse("redis"), "shared": WithShared("redis")} {
+		if _, err := p.RunRedisCluster(3, 0, opt); err == nil || !strings.Contains(err.Error(), "WithReuse") {
+			t.Errorf("cluster %s: unexpected error %v", name, err)
+		}
+		if _, err := p.RunRedisSentinel("mymaster", 1, 3, opt); err == nil || !strings.Contains(err.Error(), "WithReuse") {
+			t.Errorf("sentinel %s: unexpected error %v", name, err)
+		}
+	}
+}
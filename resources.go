@@ -0,0 +1,234 @@
+package dockertest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// minMemory is the smallest memory limit docker accepts.
+const minMemory = 6 << 20
+
+// Ulimit is a resource limit set with --ulimit.
+type Ulimit struct {
+	Name string
+	Soft int64
+	Hard int64
+}
+
+// ulimitNames are the limits docker run accepts.
+var ulimitNames = map[string]bool{
+	"core": true, "cpu": true, "data": true, "fsize": true, "locks": true, "memlock": true,
+	"msgqueue": true, "nice": true, "nofile": true, "nproc": true, "rss": true, "rtprio": true,
+	"rttime": true, "sigpending": true, "stack": true, "as": true,
+}
+
+// capabilities are the Linux capabilities that can be added or dropped, without the CAP_ prefix.
+var capabilities = map[string]bool{
+	"ALL": true, "AUDIT_CONTROL": true, "AUDIT_READ": true, "AUDIT_WRITE": true, "BLOCK_SUSPEND": true,
+	"BPF": true, "CHECKPOINT_RESTORE": true, "CHOWN": true, "DAC_OVERRIDE": true, "DAC_READ_SEARCH": true,
+	"FOWNER": true, "FSETID": true, "IPC_LOCK": true, "IPC_OWNER": true, "KILL": true, "LEASE": true,
+	"LINUX_IMMUTABLE": true, "MAC_ADMIN": true, "MAC_OVERRIDE": true, "MKNOD": true, "NET_ADMIN": true,
+	"NET_BIND_SERVICE": true, "NET_BROADCAST": true, "NET_RAW": true, "PERFMON": true, "SETFCAP": true,
+	"SETGID": true, "SETPCAP": true, "SETUID": true, "SYS_ADMIN": true, "SYS_BOOT": true, "SYS_CHROOT": true,
+	"SYS_MODULE": true, "SYS_NICE": true, "SYS_PACCT": true, "SYS_PTRACE": true, "SYS_RAWIO": true,
+	"SYS_RESOURCE": true, "SYS_TIME": true, "SYS_TTY_CONFIG": true, "SYSLOG": true, "WAKE_ALARM": true,
+}
+
+// namespacedSysctls are the sysctl prefixes docker allows to be set per container.
+var namespacedSysctls = []string{"kernel.msgmax", "kernel.msgmnb", "kernel.msgmni", "kernel.sem", "kernel.shmall",
+	"kernel.shmmax", "kernel.shmmni", "kernel.shm_rmid_forced", "fs.mqueue.", "net."}
+
+// restartPolicies are the restart policies docker run accepts.
+var restartPolicies = map[string]bool{"no": true, "always": true, "unless-stopped": true, "on-failure": true}
+
+// WithMemory limits the memory of the container to bytes.
+func WithMemory(bytes int64) RunOption {
+	return func(o *RunOptions) error {
+		if bytes < minMemory {
+			return fmt.Errorf("memory limit must be at least %d bytes, got %d", minMemory, bytes)
+		}
+		o.Memory = bytes
+		return nil
+	}
+}
+
+// WithCPUs limits the container to the given number of CPUs, for example 1.5.
+func WithCPUs(cpus float64) RunOption {
+	return func(o *RunOptions) error {
+		if cpus <= 0 {
+			return fmt.Errorf("CPU limit must be positive, got %v", cpus)
+		}
+		o.CPUs = cpus
+		return nil
+	}
+}
+
+// WithShmSize sets the size of /dev/shm in bytes.
+func WithShmSize(bytes int64) RunOption {
+	return func(o *RunOptions) error {
+		if bytes <= 0 {
+			return fmt.Errorf("shm size must be positive, got %d", bytes)
+		}
+		o.ShmSize = bytes
+		return nil
+	}
+}
+
+// WithUlimit sets the soft and hard limit of the resource name, for example "nofile". A limit
+// of -1 means unlimited.
+func WithUlimit(name string, soft, hard int64) RunOption {
+	return func(o *RunOptions) error {
+		if !ulimitNames[name] {
+			return fmt.Errorf("unknown ulimit %q", name)
+		}
+		if soft < -1 || hard < -1 {
+			return fmt.Errorf("limits of ulimit %s must be -1 or greater, got %d:%d", name, soft, hard)
+		}
+		if hard != -1 && (soft == -1 || soft > hard) {
+			return fmt.Errorf("soft limit %d of ulimit %s exceeds hard limit %d", soft, name, hard)
+		}
+		for i, u := range o.Ulimits {
+			if u.Name == name {
+				o.Ulimits = append(o.Ulimits[:i], o.Ulimits[i+1:]...)
+				break
+			}
+		}
+		o.Ulimits = append(o.Ulimits, Ulimit{Name: name, Soft: soft, Hard: hard})
+		return nil
+	}
+}
+
+// WithPrivileged runs the container in privileged mode.
+func WithPrivileged() RunOption {
+	return func(o *RunOptions) error {
+		o.Privileged = true
+		return nil
+	}
+}
+
+// capability normalizes the name of a capability and returns an error if it is unknown.
+func capability(name string) (string, error) {
+	c := strings.TrimPrefix(strings.ToUpper(name), "CAP_")
+	if !capabilities[c] {
+		return "", fmt.Errorf("unknown capability %q", name)
+	}
+	return c, nil
+}
+
+// WithCapAdd adds Linux capabilities, for example "NET_ADMIN".
+func WithCapAdd(caps ...string) RunOption {
+	return func(o *RunOptions) error {
+		for _, name := range caps {
+			c, err := capability(name)
+			if err != nil {
+				return err
+			}
+			o.CapAdd = append(o.CapAdd, c)
+		}
+		return nil
+	}
+}
+
+// WithCapDrop drops Linux capabilities.
+func WithCapDrop(caps ...string) RunOption {
+	return func(o *RunOptions) error {
+		for _, name := range caps {
+			c, err := capability(name)
+			if err != nil {
+				return err
+			}
+			o.CapDrop = append(o.CapDrop, c)
+		}
+		return nil
+	}
+}
+
+// WithSysctl sets a namespaced kernel parameter, for example "net.core.somaxconn".
+func WithSysctl(key, value string) RunOption {
+	return func(o *RunOptions) error {
+		ok := false
+		for _, prefix := range namespacedSysctls {
+			if key == prefix || (strings.HasSuffix(prefix, ".") && strings.HasPrefix(key, prefix)) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("sysctl %q is not namespaced and cannot be set per container", key)
+		}
+		if o.Sysctls == nil {
+			o.Sysctls = map[string]string{}
+		}
+		o.Sysctls[key] = value
+		return nil
+	}
+}
+
+// WithInit runs an init process in the container that forwards signals and reaps zombies.
+func WithInit() RunOption {
+	return func(o *RunOptions) error {
+		o.Init = true
+		return nil
+	}
+}
+
+// WithRestartPolicy sets the restart policy, one of "no", "always", "unless-stopped" and
+// "on-failure". maxRetries limits the restarts of "on-failure" and must be zero otherwise.
+func WithRestartPolicy(policy string, maxRetries int) RunOption {
+	return func(o *RunOptions) error {
+		if !restartPolicies[policy] {
+			return fmt.Errorf("unknown restart policy %q", policy)
+		}
+		if maxRetries < 0 || (maxRetries > 0 && policy != "on-failure") {
+			return fmt.Errorf("max retries %d not allowed for restart policy %s", maxRetries, policy)
+		}
+		o.RestartPolicy = policy
+		if maxRetries > 0 {
+			o.RestartPolicy += ":" + strconv.Itoa(maxRetries)
+		}
+		return nil
+	}
+}
+
+// resourceArgs returns the "docker run" arguments for the resource limits and runtime options.
+func (o *RunOptions) resourceArgs() []string {
+	var args []string
+	if o.Memory > 0 {
+		args = append(args, "--memory", strconv.FormatInt(o.Memory, 10))
+	}
+	if o.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(o.CPUs, 'f', -1, 64))
+	}
+	if o.ShmSize > 0 {
+		args = append(args, "--shm-size", strconv.FormatInt(o.ShmSize, 10))
+	}
+	for _, u := range o.Ulimits {
+		args = append(args, "--ulimit", fmt.Sprintf("%s=%d:%d", u.Name, u.Soft, u.Hard))
+	}
+	if o.Privileged {
+		args = append(args, "--privileged")
+	}
+	for _, c := range o.CapAdd {
+		args = append(args, "--cap-add", c)
+	}
+	for _, c := range o.CapDrop {
+		args = append(args, "--cap-drop", c)
+	}
+	keys := make([]string, 0, len(o.Sysctls))
+	for k := range o.Sysctls {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--sysctl", k+"="+o.Sysctls[k])
+	}
+	if o.Init {
+		args = append(args, "--init")
+	}
+	if o.RestartPolicy != "" {
+		args = append(args, "--restart", o.RestartPolicy)
+	}
+	return args
+}
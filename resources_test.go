@@ -0,0 +1,54 @@
+package dockertest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResourceArgs(t *testing.T) {
+	o, err := newRunOptions([]RunOption{
+		WithMemory(512 << 20),
+		WithCPUs(1.5),
+		WithShmSize(64 << 20),
+		WithUlimit("nofile", 1024, 4096),
+		WithUlimit("memlock", -1, -1),
+		WithCapAdd("cap_net_admin"),
+		WithCapDrop("MKNOD"),
+		WithSysctl("net.core.somaxconn", "1024"),
+		WithSysctl("kernel.shmmax", "1"),
+		WithInit(),
+		WithRestartPolicy("on-failure", 3),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"--memory", "536870912", "--cpus", "1.5", "--shm-size", "67108864",
+		"--ulimit", "nofile=1024:4096", "--ulimit", "memlock=-1:-1", "--cap-add", "NET_ADMIN", "--cap-drop", "MKNOD",
+		"--sysctl", "kernel.shmmax=1", "--sysctl", "net.core.somaxconn=1024",
+		"--init", "--restart", "on-failure:3",
+	}
+	if got := o.resourceArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestInvalidResourceOptions(t *testing.T) {
+	for name, opt := range map[string]RunOption{
+		"memory":         WithMemory(1024),
+		"cpus":           WithCPUs(0),
+		"shm":            WithShmSize(-1),
+		"ulimit name":    WithUlimit("files", 1, 1),
+		"ulimit soft":    WithUlimit("nofile", 2, 1),
+		"ulimit soft -1": WithUlimit("nofile", -1, 1),
+		"ulimit range":   WithUlimit("nofile", -2, 1),
+		"capability":     WithCapAdd("FLY"),
+		"sysctl":         WithSysctl("vm.swappiness", "0"),
+		"restart":        WithRestartPolicy("sometimes", 0),
+		"restart always": WithRestartPolicy("always", 3),
+	} {
+		if _, err := newRunOptions([]RunOption{opt}); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
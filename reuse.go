@@ -0,0 +1,151 @@
+package dockertest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// reuseLabel is set on containers run in reuse mode. Its value is the hash identifying the container.
+	reuseLabel = "io.dockertest.reuse"
+	// reuseNamePrefix prefixes the names of containers run in reuse mode.
+	reuseNamePrefix = "dockertest-"
+	// reuseProbeTimeout is how long an existing container may take to answer before it is replaced.
+	reuseProbeTimeout = 5 * time.Second
+)
+
+// reuseHash identifies a container by its reuse key and the arguments it is run with. Random
+// host ports are hashed as 0, and networks are left out as their names differ between runs;
+// reattach connects reused containers to them instead.
+func (o *RunOptions) reuseHash(image string, containerPort int) string {
+	ports := map[int]int{}
+	for _, p := range append([]int{containerPort}, o.Ports...) {
+		ports[p] = o.HostPorts[p]
+	}
+	c := *o
+	c.Networks = nil
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00", o.ReuseKey, containerPort)
+	// The arguments start with the name, which is derived from the hash.
+	for _, arg := range c.runArgs("", image, ports)[2:] {
+		fmt.Fprintf(h, "%q\x00", arg)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// withLabel returns a copy of o with an additional label.
+func (o *RunOptions) withLabel(key, value string) *RunOptions {
+	c := *o
+	c.Labels = map[string]string{key: value}
+	for k, v := range o.Labels {
+		c.Labels[k] = v
+	}
+	return &c
+}
+
+// reattach looks for a container created in reuse mode with the given hash. It returns nil
+// if there is none or if it could not be revived, in which case it is removed.
+func (p *Pool) reattach(image string, containerPort int, o *RunOptions, hash string) (*Container, error) {
+	l := p.log()
+	out, err := runDockerCommand(l, "docker", "ps", "-a", "-q", "--no-trunc", "--filter", "label="+reuseLabel+"="+hash).Output()
+	if err != nil {
+		return nil, fmt.Errorf("Error listing reusable containers: %v", err)
+	}
+	id := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	if id == "" {
+		return nil, nil
+	}
+	c := ContainerID(id)
+
+	running, err := runDockerCommand(l, "docker", "inspect", "-f", "{{.State.Running}}", id).Output()
+	if err != nil {
+		return nil, fmt.Errorf("Error inspecting reusable container %s: %v", id, err)
+	}
+	if strings.TrimSpace(string(running)) != "true" {
+		l.Infof("starting stopped reusable container %s (%s)", id, image)
+		if err := runDockerCommand(l, "docker", "start", id).Run(); err != nil {
+			l.Warnf("could not start reusable container %s, replacing it: %v", id, err)
+			forceRemove(l, c)
+			return nil, nil
+		}
+	}
+
+	// The networks are not part of the hash and may have been created after the container.
+	if err := connectNetworks(l, c, o.Networks); err != nil {
+		l.Warnf("could not connect reusable container %s to its networks, replacing it: %v", id, err)
+		forceRemove(l, c)
+		return nil, nil
+	}
+
+	ports := map[int]int{}
+	for _, p := range append([]int{containerPort}, o.Ports...) {
+		if ports[p], err = publishedPort(l, c, p); err != nil {
+			break
+		}
+	}
+	port := ports[containerPort]
+	if err == nil {
+		var ip string
+		if ip, err = c.lookup(l, port, reuseProbeTimeout); err == nil {
+			con := &Container{ContainerID: c, Image: image, Host: ip, Port: port, ContainerPort: containerPort, name: reuseNamePrefix + hash, ports: ports, pool: p, options: o.withLabel(reuseLabel, hash), reused: true}
+			if o.Wait != nil {
+				err = o.Wait.WaitUntilReady(con, reuseProbeTimeout)
+			}
+			if err == nil {
+				l.Infof("reusing container %s (%s)", id, image)
+				return p.track(con), nil
+			}
+		}
+	}
+	l.Warnf("reusable container %s is not healthy, replacing it: %v", id, err)
+	forceRemove(l, c)
+	return nil, nil
+}
+
+// connectNetworks connects c to the networks it is not attached to yet.
+func connectNetworks(l Logger, c ContainerID, networks []NetworkAttachment) error {
+	if len(networks) == 0 {
+		return nil
+	}
+	out, err := runDockerCommand(l, "docker", "inspect", "-f", "{{range $k, $v := .NetworkSettings.Networks}}{{$k}} {{end}}", string(c)).Output()
+	if err != nil {
+		return fmt.Errorf("Error inspecting networks of %s: %v", c, err)
+	}
+	attached := map[string]bool{}
+	for _, name := range strings.Fields(string(out)) {
+		attached[name] = true
+	}
+	for _, n := range networks {
+		if attached[n.Network.Name] {
+			continue
+		}
+		if err := n.Network.connect(c, n.Aliases); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forceRemove removes c whatever its state, so that its name can be used again.
+func forceRemove(l Logger, c ContainerID) error {
+	return runDockerCommand(l, "docker", "rm", "-f", "-v", string(c)).Run()
+}
+
+// publishedPort returns the host port containerPort of c is published on.
+func publishedPort(l Logger, c ContainerID, containerPort int) (int, error) {
+	out, err := runDockerCommand(l, "docker", "port", string(c), fmt.Sprintf("%d/tcp", containerPort)).Output()
+	if err != nil {
+		return 0, fmt.Errorf("Error looking up port %d of %s: %v", containerPort, c, err)
+	}
+	line := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	_, port, err := net.SplitHostPort(line)
+	if err != nil {
+		return 0, fmt.Errorf("Unexpected output from docker port: %q", out)
+	}
+	return strconv.Atoi(port)
+}
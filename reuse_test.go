@@ -0,0 +1,89 @@
+package dockertest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReuseHash(t *testing.T) {
+	opts := func(o ...RunOption) *RunOptions {
+		r, err := newRunOptions(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	a := opts(WithReuse("pg"), WithEnv("A=1"), WithLabel("x", "1"), WithLabel("y", "2")).reuseHash("postgres", 5432)
+	b := opts(WithLabel("y", "2"), WithLabel("x", "1"), WithEnv("A=1"), WithReuse("pg")).reuseHash("postgres", 5432)
+	if a != b {
+		t.Errorf("hash depends on option order: %s != %s", a, b)
+	}
+	for name, o := range map[string]*RunOptions{
+		"key":    opts(WithReuse("other"), WithEnv("A=1"), WithLabel("x", "1"), WithLabel("y", "2")),
+		"env":    opts(WithReuse("pg"), WithEnv("A=2"), WithLabel("x", "1"), WithLabel("y", "2")),
+		"label":  opts(WithReuse("pg"), WithEnv("A=1"), WithLabel("x", "1")),
+		"mounts": opts(WithReuse("pg"), WithEnv("A=1"), WithLabel("x", "1"), WithLabel("y", "2"), WithTmpfs("/var/lib/postgresql/data", 0)),
+		"memory": opts(WithReuse("pg"), WithEnv("A=1"), WithLabel("x", "1"), WithLabel("y", "2"), WithMemory(512<<20)),
+		"ulimit": opts(WithReuse("pg"), WithEnv("A=1"), WithLabel("x", "1"), WithLabel("y", "2"), WithUlimit("nofile", 1024, 4096)),
+		"init":   opts(WithReuse("pg"), WithEnv("A=1"), WithLabel("x", "1"), WithLabel("y", "2"), WithInit()),
+		"port":   opts(WithReuse("pg"), WithEnv("A=1"), WithLabel("x", "1"), WithLabel("y", "2"), WithHostPort(5432, 15432)),
+	} {
+		if h := o.reuseHash("postgres", 5432); h == a {
+			t.Errorf("hash does not depend on %s", name)
+		}
+	}
+	if h := opts(WithReuse("pg"), WithEnv("A=1"), WithLabel("x", "1"), WithLabel("y", "2"), WithNetwork(&Network{Name: "dockertest-1"}, "db")).reuseHash("postgres", 5432); h != a {
+		t.Error("hash depends on the networks, whose names differ between runs")
+	}
+	if h := opts(WithReuse("pg"), WithEnv("A=1"), WithLabel("x", "1"), WithLabel("y", "2")).reuseHash("postgres:9.6", 5432); h == a {
+		t.Error("hash does not depend on image")
+	}
+}
+
+func TestReattachConnectsNetworks(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	bind := BindDockerToLocalhost
+	BindDockerToLocalhost = "1"
+	defer func() { BindDockerToLocalhost = bind }()
+
+	calls := filepath.Join(t.TempDir(), "calls")
+	fakeDocker(t, fmt.Sprintf(`case "$1" in
+ps) echo abc123 ;;
+inspect) case "$3" in
+	"{{.State.Running}}") echo true ;;
+	*) echo bridge dockertest-old ;;
+	esac ;;
+port) echo %s ;;
+network) echo "$@" >> %s ;;
+esac
+`, ln.Addr(), calls))
+
+	p := &Pool{Logger: NopLogger}
+	o, err := newRunOptions([]RunOption{
+		WithReuse("pg"),
+		WithNetwork(&Network{Name: "dockertest-old", pool: p}, "old"),
+		WithNetwork(&Network{Name: "dockertest-new", pool: p}, "db", "postgres"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := p.reattach("postgres", 5432, o, o.reuseHash("postgres", 5432))
+	if err != nil || c == nil {
+		t.Fatalf("container not reattached: %v", err)
+	}
+	out, err := ioutil.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(string(out)), "network connect --alias db --alias postgres dockertest-new abc123"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,183 @@
+package dockertest
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Service defines how to run a service such as PostgreSQL, so that it can be run by name with
+// RunService. The built-in services are registered under the names "mongo", "mysql", "mariadb",
+// "postgres", "elasticsearch", "redis", "nats", "fluentd", "kafka", "minio" and "rabbitmq";
+// register your own with Register.
+type Service struct {
+	// Name is the name the service is registered under.
+	Name string
+	// Image is the image without tag.
+	Image string
+	// Tag is the default tag of the image. If empty, docker uses "latest".
+	Tag string
+	// Ports are the container ports to publish. The first one is the main port of the container.
+	Ports []int
+	// Env holds the KEY=value environment variables the service needs.
+	Env []string
+	// Cmd holds the arguments passed after the image name.
+	Cmd []string
+	// Options are applied after Env and Cmd, but before the options given to RunService.
+	Options []RunOption
+	// Wait decides when the service is ready, in addition to its main port being reachable.
+	Wait WaitStrategy
+	// Username and Password are the credentials clients connect with, if the service needs any.
+	Username string
+	Password string
+	// ConnectionString returns the address or URL clients connect to the running container with.
+	ConnectionString func(c *Container) string
+}
+
+// ImageRef returns the image with its default tag.
+func (s *Service) ImageRef() string {
+	return imageRef(s.Image, s.Tag)
+}
+
+func imageRef(image, tag string) string {
+	if tag == "" {
+		return image
+	}
+	return image + ":" + tag
+}
+
+var (
+	servicesMu sync.RWMutex
+	services   = map[string]*Service{}
+)
+
+// Register adds s to the registry of services. It returns an error if the definition is
+// incomplete or a service of the same name is already registered.
+func Register(s *Service) error {
+	if s.Name == "" || s.Image == "" {
+		return fmt.Errorf("service must have a name and an image")
+	}
+	if len(s.Ports) == 0 {
+		return fmt.Errorf("service %s must have at least one port", s.Name)
+	}
+	servicesMu.Lock()
+	defer servicesMu.Unlock()
+	if _, ok := services[s.Name]; ok {
+		return fmt.Errorf("service %s is already registered", s.Name)
+	}
+	services[s.Name] = s
+	return nil
+}
+
+// MustRegister is like Register but panics on error. It is meant for init functions.
+func MustRegister(s *Service) {
+	if err := Register(s); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the service registered under name.
+func Lookup(name string) (*Service, bool) {
+	servicesMu.RLock()
+	defer servicesMu.RUnlock()
+	s, ok := services[name]
+	return s, ok
+}
+
+// Services returns the names of all registered services in alphabetical order.
+func Services() []string {
+	servicesMu.RLock()
+	defer servicesMu.RUnlock()
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithTag overrides the default tag of the image when running a registered service.
+func WithTag(tag string) RunOption {
+	return func(o *RunOptions) error {
+		if tag == "" {
+			return fmt.Errorf("tag must not be empty")
+		}
+		o.Tag = tag
+		return nil
+	}
+}
+
+// RunService runs the registered service name with the default pool, see Pool.RunService.
+func RunService(name string, opts ...RunOption) (*Container, error) {
+	return defaultPool.RunService(name, opts...)
+}
+
+// RunService runs the registered service name. The given options are applied after the
+// options of the service definition.
+func (p *Pool) RunService(name string, opts ...RunOption) (*Container, error) {
+	s, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown service %s", name)
+	}
+	return p.runService(s, s.Ports[0], opts...)
+}
+
+// runService runs s with containerPort as its main port instead of the first port of the
+// definition, for services that must listen on the port they are published on.
+func (p *Pool) runService(s *Service, containerPort int, opts ...RunOption) (*Container, error) {
+	var all []RunOption
+	if len(s.Env) > 0 {
+		all = append(all, WithEnv(s.Env...))
+	}
+	if len(s.Cmd) > 0 {
+		all = append(all, WithCmd(s.Cmd...))
+	}
+	if len(s.Ports) > 1 {
+		all = append(all, WithPorts(s.Ports[1:]...))
+	}
+	if s.Wait != nil {
+		all = append(all, WithWait(s.Wait))
+	}
+	all = append(append(all, s.Options...), opts...)
+
+	// The tag is only known once the options have been applied.
+	o, err := newRunOptions(all)
+	if err != nil {
+		return nil, err
+	}
+	tag := s.Tag
+	if o.Tag != "" {
+		tag = o.Tag
+	}
+	c, err := p.Run(imageRef(s.Image, tag), containerPort, all...)
+	if err != nil {
+		return nil, err
+	}
+	c.service = s
+	return c, nil
+}
+
+// Service returns the definition of the registered service the container runs, or nil if it
+// was not run with RunService.
+func (c *Container) Service() *Service {
+	return c.service
+}
+
+// ConnectionString returns the address or URL clients connect to the service with. It is empty
+// if the container was not run with RunService or the service does not define one.
+func (c *Container) ConnectionString() string {
+	if c.service == nil || c.service.ConnectionString == nil {
+		return ""
+	}
+	return c.service.ConnectionString(c)
+}
+
+// setupService runs the registered service name with the default pool and the given arguments,
+// for the Setup functions.
+func setupService(name string, args ...string) (c ContainerID, ip string, port int, err error) {
+	con, err := RunService(name, WithCmd(args...))
+	if err != nil {
+		return "", "", 0, err
+	}
+	return con.ContainerID, con.Host, con.Port, nil
+}
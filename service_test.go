@@ -0,0 +1,30 @@
+package dockertest
+
+import "testing"
+
+func TestRegister(t *testing.T) {
+	for _, name := range []string{"mongo", "mysql", "mariadb", "postgres", "elasticsearch", "redis", "nats", "fluentd", "kafka", "minio", "rabbitmq"} {
+		if _, ok := Lookup(name); !ok {
+			t.Errorf("built-in service %s not registered", name)
+		}
+	}
+	if err := Register(&Service{Name: "postgres", Image: "postgres", Ports: []int{5432}}); err == nil {
+		t.Error("registered postgres twice")
+	}
+	if err := Register(&Service{Name: "noports", Image: "busybox"}); err == nil {
+		t.Error("registered service without ports")
+	}
+
+	s := &Service{Name: "test-echo", Image: "hashicorp/http-echo", Tag: "0.2.3", Ports: []int{5678}}
+	if err := Register(s); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		servicesMu.Lock()
+		delete(services, s.Name)
+		servicesMu.Unlock()
+	}()
+	if got, ok := Lookup("test-echo"); !ok || got.ImageRef() != "hashicorp/http-echo:0.2.3" {
+		t.Errorf("unexpected lookup result %+v", got)
+	}
+}
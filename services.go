@@ -0,0 +1,27 @@
+package dockertest
+
+func init() {
+	MustRegister(&Service{
+		Name:             "mongo",
+		Image:            mongoImage,
+		Ports:            []int{27017},
+		ConnectionString: mongoURI,
+	})
+	MustRegister(&Service{
+		Name:     "postgres",
+		Image:    postgresImage,
+		Ports:    []int{5432},
+		Env:      []string{"POSTGRES_PASSWORD=" + PostgresPassword},
+		Username: PostgresUsername,
+		Password: PostgresPassword,
+		ConnectionString: func(c *Container) string {
+			return (&PostgreSQLContainer{c}).DSN(PostgresUsername)
+		},
+	})
+	MustRegister(&Service{
+		Name:             "redis",
+		Image:            redisImage,
+		Ports:            []int{6379},
+		ConnectionString: func(c *Container) string { return "redis://" + c.Addr() },
+	})
+}
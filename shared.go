@@ -0,0 +1,135 @@
+package dockertest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WithShared shares the container between all processes using the same key, such as the test
+// binaries go test ./... runs in parallel for each package. The first process starts the container,
+// the others attach to it, and KillRemove only removes it once the last process has released it.
+// Startup is coordinated with a file lock in os.TempDir.
+func WithShared(key string) RunOption {
+	return func(o *RunOptions) error {
+		if key == "" {
+			return fmt.Errorf("shared key must not be empty")
+		}
+		o.SharedKey = key
+		return nil
+	}
+}
+
+// sharedState is stored next to the lock file and records the processes using a shared container.
+type sharedState struct {
+	// Refs holds the pid of the process once per reference it holds.
+	Refs []int
+}
+
+// sharedPath returns the path prefix for the lock and state files of a shared key.
+func sharedPath(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return filepath.Join(os.TempDir(), "dockertest-shared-"+hex.EncodeToString(h[:])[:16])
+}
+
+func readSharedState(path string) (*sharedState, error) {
+	s := &sharedState{}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return s, nil
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("Error reading shared container state %s: %v", path, err)
+	}
+	// Drop references of processes that died without releasing them.
+	refs := s.Refs[:0]
+	for _, pid := range s.Refs {
+		if processAlive(pid) {
+			refs = append(refs, pid)
+		}
+	}
+	s.Refs = refs
+	return s, nil
+}
+
+func (s *sharedState) write(path string) error {
+	if len(s.Refs) == 0 {
+		return os.Remove(path)
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// runShared starts or attaches to the container shared under o.SharedKey and takes a reference on it.
+func (p *Pool) runShared(image string, containerPort int, o *RunOptions) (*Container, error) {
+	path := sharedPath(o.SharedKey)
+	lock, err := lockFile(path + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("Error locking shared container %s: %v", o.SharedKey, err)
+	}
+	defer unlockFile(lock)
+
+	state, err := readSharedState(path + ".json")
+	if err != nil {
+		return nil, err
+	}
+	if o.ReuseKey == "" {
+		o.ReuseKey = "shared/" + o.SharedKey
+	}
+	c, err := p.start(image, containerPort, o)
+	if err != nil {
+		return nil, err
+	}
+	state.Refs = append(state.Refs, os.Getpid())
+	if err := state.write(path + ".json"); err != nil {
+		return nil, fmt.Errorf("Error writing shared container state: %v", err)
+	}
+	p.log().Debugf("shared container %s (%s) has %d references", o.SharedKey, c.ContainerID, len(state.Refs))
+	return c, nil
+}
+
+// release drops the reference this process holds on a shared container and removes the container
+// if it was the last one.
+func (c *Container) release() error {
+	l := c.pool.log()
+	key := c.options.SharedKey
+	path := sharedPath(key)
+	lock, err := lockFile(path + ".lock")
+	if err != nil {
+		return fmt.Errorf("Error locking shared container %s: %v", key, err)
+	}
+	defer unlockFile(lock)
+
+	state, err := readSharedState(path + ".json")
+	if err != nil {
+		return err
+	}
+	pid := os.Getpid()
+	for i, ref := range state.Refs {
+		if ref == pid {
+			state.Refs = append(state.Refs[:i], state.Refs[i+1:]...)
+			break
+		}
+	}
+	if err := state.write(path + ".json"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Error writing shared container state: %v", err)
+	}
+	if len(state.Refs) > 0 {
+		l.Debugf("shared container %s (%s) still has %d references", key, c.ContainerID, len(state.Refs))
+		return nil
+	}
+	l.Infof("removing shared container %s (%s)", key, c.ContainerID)
+	return c.ForceKillRemove()
+}
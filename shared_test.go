@@ -0,0 +1,30 @@
+package dockertest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSharedStateDropsDeadProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	// Pid 1<<30 is above any pid_max, so the process cannot exist.
+	s := &sharedState{Refs: []int{os.Getpid(), 1 << 30, os.Getpid()}}
+	if err := s.write(path); err != nil {
+		t.Fatal(err)
+	}
+	s, err := readSharedState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Refs) != 2 {
+		t.Fatalf("expected 2 live references, got %v", s.Refs)
+	}
+	s.Refs = nil
+	if err := s.write(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("state file not removed after last reference: %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package dockertest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StackService declares a container of a Stack.
+type StackService struct {
+	// Image is the image to run.
+	Image string
+	// Port is the container port to publish.
+	Port int
+	// Options are passed to Pool.Run. Use WithWait to declare when the service is ready.
+	Options []RunOption
+	// DependsOn names the services that must be ready before this one is started.
+	DependsOn []string
+}
+
+// Stack runs several containers that depend on each other. All of them are attached to a
+// network created for the stack, where they reach each other by service name.
+type Stack struct {
+	// Pool runs the containers. If nil, the default pool is used.
+	Pool *Pool
+
+	services   map[string]StackService
+	mu         sync.Mutex
+	network    *Network
+	containers map[string]*Container
+	// started holds the names of the started services in the order they became ready.
+	started []string
+}
+
+// NewStack returns an empty stack running its containers with p.
+func NewStack(p *Pool) *Stack {
+	return &Stack{Pool: p, services: map[string]StackService{}}
+}
+
+// Add declares the service name. It must be called before Up.
+func (s *Stack) Add(name string, svc StackService) *Stack {
+	if s.services == nil {
+		s.services = map[string]StackService{}
+	}
+	s.services[name] = svc
+	return s
+}
+
+func (s *Stack) pool() *Pool {
+	if s.Pool != nil {
+		return s.Pool
+	}
+	return defaultPool
+}
+
+// validate checks that all dependencies exist and that there is no cycle between them.
+func (s *Stack) validate() error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range s.services[name].DependsOn {
+			if _, ok := s.services[dep]; !ok {
+				return fmt.Errorf("service %s depends on unknown service %s", name, dep)
+			}
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, name := range s.names() {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// names returns the names of all services in a stable order.
+func (s *Stack) names() []string {
+	names := make([]string, 0, len(s.services))
+	for name := range s.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Up starts all services. Services without pending dependencies start in parallel, the others
+// as soon as all of their dependencies are ready. If a service fails, the stack is torn down
+// and the error returned.
+func (s *Stack) Up() error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+	p := s.pool()
+	n, err := p.CreateNetwork("")
+	if err != nil {
+		return err
+	}
+	s.network = n
+	s.containers = map[string]*Container{}
+
+	ready := map[string]chan struct{}{}
+	for name := range s.services {
+		ready[name] = make(chan struct{})
+	}
+	errs := make(map[string]error)
+	var wg sync.WaitGroup
+	for name, svc := range s.services {
+		wg.Add(1)
+		go func(name string, svc StackService) {
+			defer wg.Done()
+			defer close(ready[name])
+			for _, dep := range svc.DependsOn {
+				<-ready[dep]
+				s.mu.Lock()
+				_, ok := s.containers[dep]
+				s.mu.Unlock()
+				if !ok {
+					s.fail(errs, name, fmt.Errorf("dependency %s did not start", dep))
+					return
+				}
+			}
+			opts := append([]RunOption{WithNetwork(n, name)}, svc.Options...)
+			c, err := p.Run(svc.Image, svc.Port, opts...)
+			if err != nil {
+				s.fail(errs, name, err)
+				return
+			}
+			s.mu.Lock()
+			s.containers[name] = c
+			s.started = append(s.started, name)
+			s.mu.Unlock()
+		}(name, svc)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		s.Down()
+		msgs := make([]string, 0, len(errs))
+		for name, err := range errs {
+			msgs = append(msgs, fmt.Sprintf("%s: %v", name, err))
+		}
+		sort.Strings(msgs)
+		return fmt.Errorf("Error starting stack: %s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+func (s *Stack) fail(errs map[string]error, name string, err error) {
+	s.mu.Lock()
+	errs[name] = err
+	s.mu.Unlock()
+}
+
+// Container returns the container of the service name, or nil if it is not running.
+func (s *Stack) Container(name string) *Container {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.containers[name]
+}
+
+// Endpoints returns the host:port address of every running service by name.
+func (s *Stack) Endpoints() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	endpoints := make(map[string]string, len(s.containers))
+	for name, c := range s.containers {
+		endpoints[name] = c.Addr()
+	}
+	return endpoints
+}
+
+// Network returns the network the services are attached to.
+func (s *Stack) Network() *Network {
+	return s.network
+}
+
+// Down removes the containers in the reverse order they were started in, and then the network.
+// It returns the first error, but tries to remove everything.
+func (s *Stack) Down() error {
+	s.mu.Lock()
+	started, containers := s.started, s.containers
+	s.started, s.containers = nil, nil
+	s.mu.Unlock()
+
+	var first error
+	for i := len(started) - 1; i >= 0; i-- {
+		if err := containers[started[i]].KillRemove(); err != nil && first == nil {
+			first = err
+		}
+	}
+	if s.network != nil {
+		if err := s.pool().RemoveNetwork(s.network); err != nil && first == nil {
+			first = err
+		}
+		s.network = nil
+	}
+	return first
+}
@@ -0,0 +1,39 @@
+package dockertest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStackValidate(t *testing.T) {
+	for name, c := range map[string]struct {
+		stack *Stack
+		err   string
+	}{
+		"ok": {
+			NewStack(nil).
+				Add("app", StackService{DependsOn: []string{"db", "cache"}}).
+				Add("db", StackService{}).
+				Add("cache", StackService{}),
+			"",
+		},
+		"unknown": {
+			NewStack(nil).Add("app", StackService{DependsOn: []string{"db"}}),
+			"service app depends on unknown service db",
+		},
+		"cycle": {
+			NewStack(nil).
+				Add("a", StackService{DependsOn: []string{"b"}}).
+				Add("b", StackService{DependsOn: []string{"c"}}).
+				Add("c", StackService{DependsOn: []string{"a"}}),
+			"dependency cycle: a -> b -> c -> a",
+		},
+	} {
+		err := c.stack.validate()
+		if c.err == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+		} else if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("%s: expected error %q, got %v", name, c.err, err)
+		}
+	}
+}
@@ -17,16 +17,25 @@ var (
 	// You can set this variable either directly or by defining a DOCKERTEST_BIND_LOCALHOST env variable.
 	// FIXME DOCKER_BIND_LOCALHOST remove legacy support
 	BindDockerToLocalhost = env.Getenv("DOCKERTEST_BIND_LOCALHOST", env.Getenv("DOCKER_BIND_LOCALHOST", ""))
+
+	// ComposeCommand is the command used to run compose projects, for example "docker compose". If empty,
+	// docker-compose is used if it is on the PATH and "docker compose" otherwise.
+	// You can set this variable either directly or by defining a DOCKERTEST_COMPOSE env variable.
+	ComposeCommand = env.Getenv("DOCKERTEST_COMPOSE", "")
 )
 
 const (
 	mongoImage         = "mongo"
 	mysqlImage         = "mysql"
+	mariadbImage       = "mariadb"
 	postgresImage      = "postgres"
 	elasticsearchImage = "elasticsearch"
 	redisImage         = "redis"
 	natsImage          = "nats"
 	fluentdImage       = "fluent/fluentd"
+	minioImage         = "minio/minio"
+	rabbitmqImage      = "rabbitmq"
+	kafkaImage         = "apache/kafka"
 
 	// MySQLUsername must be passed as username when connecting to mysql
 	MySQLUsername = "root"
@@ -38,4 +47,20 @@ const (
 	PostgresUsername = "postgres"
 	// PostgresPassword must be passed as password when connecting to postgres
 	PostgresPassword = "docker"
+
+	// MinIOAccessKey is the default access key of the minio root user
+	MinIOAccessKey = "minioadmin"
+	// MinIOSecretKey is the default secret key of the minio root user
+	MinIOSecretKey = "minioadmin"
+
+	// RabbitMQUsername is the default user when connecting to rabbitmq. The guest user
+	// of the image may only connect from inside the container.
+	RabbitMQUsername = "dockertest"
+	// RabbitMQPassword is the default password when connecting to rabbitmq
+	RabbitMQPassword = "dockertest"
+
+	// ElasticsearchUsername must be passed as username when connecting to elasticsearch with security enabled
+	ElasticsearchUsername = "elastic"
+	// ElasticsearchPassword must be passed as password when connecting to elasticsearch with security enabled
+	ElasticsearchPassword = "dockertest"
 )
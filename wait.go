@@ -0,0 +1,131 @@
+package dockertest
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WaitStrategy decides when a container is ready to be used. Pools first wait until the
+// container's published port accepts TCP connections, and then for the container's strategy.
+type WaitStrategy interface {
+	// WaitUntilReady blocks until c is ready. It returns an error if c is not ready before timeout.
+	WaitUntilReady(c *Container, timeout time.Duration) error
+}
+
+// WaitFunc adapts a function to a WaitStrategy.
+type WaitFunc func(c *Container, timeout time.Duration) error
+
+// WaitUntilReady calls f.
+func (f WaitFunc) WaitUntilReady(c *Container, timeout time.Duration) error {
+	return f(c, timeout)
+}
+
+// WithWait sets the strategy deciding when the container is ready.
+func WithWait(s WaitStrategy) RunOption {
+	return func(o *RunOptions) error {
+		if s == nil {
+			return fmt.Errorf("wait strategy must not be nil")
+		}
+		o.Wait = s
+		return nil
+	}
+}
+
+// retry calls f regularly until it returns nil, and returns its last error after timeout.
+func retry(timeout time.Duration, f func() error) error {
+	done := time.Now().Add(timeout)
+	for {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(done) {
+			return fmt.Errorf("not ready after %v: %v", timeout, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// WaitForLog waits until the container's output contains text at least occurrences times.
+// After a restart, only the output since the restart counts.
+func WaitForLog(text string, occurrences int) WaitStrategy {
+	return WaitFunc(func(c *Container, timeout time.Duration) error {
+		return retry(timeout, func() error {
+			out, err := c.startLogs()
+			if err != nil {
+				return err
+			}
+			if n := bytes.Count(out, []byte(text)); n < occurrences {
+				return fmt.Errorf("%q logged %d of %d times", text, n, occurrences)
+			}
+			return nil
+		})
+	})
+}
+
+// WaitForHTTP waits until a GET request for path on the container's published port returns status.
+func WaitForHTTP(path string, status int) WaitStrategy {
+	return WaitForHTTPOnPort(0, path, status)
+}
+
+// WaitForHTTPOnPort is like WaitForHTTP, but sends the request to the host port containerPort is
+// published on, such as a monitoring port published with WithPorts. If containerPort is 0, the
+// container's main port is used.
+func WaitForHTTPOnPort(containerPort int, path string, status int) WaitStrategy {
+	return WaitFunc(func(c *Container, timeout time.Duration) error {
+		client := &http.Client{Timeout: 5 * time.Second}
+		addr := c.Addr()
+		if containerPort != 0 {
+			addr = c.PortAddr(containerPort)
+		}
+		url := "http://" + addr + "/" + strings.TrimPrefix(path, "/")
+		return retry(timeout, func() error {
+			resp, err := client.Get(url)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode != status {
+				return fmt.Errorf("GET %s returned %d, want %d", url, resp.StatusCode, status)
+			}
+			return nil
+		})
+	})
+}
+
+// WaitForExec waits until cmd exits successfully inside the container.
+func WaitForExec(cmd ...string) WaitStrategy {
+	return WaitFunc(func(c *Container, timeout time.Duration) error {
+		return retry(timeout, func() error {
+			_, err := c.Exec(cmd...)
+			return err
+		})
+	})
+}
+
+// Logs returns the container's combined standard output and standard error.
+func (c *Container) Logs() ([]byte, error) {
+	return c.logs()
+}
+
+// startLogs returns the output of the container since it was last started by Start, Restart or
+// Restore, or all of it if it was not restarted. Wait strategies read these, so that output
+// from before a restart does not make them pass.
+func (c *Container) startLogs() ([]byte, error) {
+	if c.startedAt == "" {
+		return c.logs()
+	}
+	return c.logs("--since", c.startedAt)
+}
+
+func (c *Container) logs(args ...string) ([]byte, error) {
+	args = append(append([]string{"logs"}, args...), string(c.ContainerID))
+	out, err := runDockerCommand(c.pool.log(), "docker", args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading logs of %s: %v: %s", c.ContainerID, err, out)
+	}
+	return out, nil
+}